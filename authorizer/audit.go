@@ -0,0 +1,286 @@
+package authorizer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// AuditEvent is a structured record of an authorization decision made by the interceptors
+type AuditEvent struct {
+	// Time is the time the request was authorized
+	Time time.Time
+	// Method is the grpc method
+	Method string
+	// Peer is the address of the remote peer (the target of the connection for client interceptors)
+	Peer string
+	// User is the user extracted from the context
+	User any
+	// UserID is the identity of the user returned by the UserIdentifier
+	UserID string
+	// Metadata is the subset of the request metadata configured with WithAuditMetadataKeys
+	Metadata metadata.MD
+	// IsStream is true if the grpc handler is a streaming handler
+	IsStream bool
+	// MessageIndex is the index of the message in the stream when stream messages are authorized individually
+	MessageIndex int
+	// Decision is the decision of the authorizer. It is nil if Err is set
+	Decision *Decision
+	// Latency is the time it took to authorize the request
+	Latency time.Duration
+	// Err is the error returned by the user extractor or the authorizer
+	Err error
+	// DryRun is true if the decision was not enforced (see WithDryRun)
+	DryRun bool
+}
+
+// Auditor receives an AuditEvent for every authorization decision made by the interceptors
+type Auditor interface {
+	// Audit is called after every authorization decision. It must not block the request for long
+	Audit(ctx context.Context, event *AuditEvent)
+}
+
+// AuditorFunc is a function that audits authorization decisions
+type AuditorFunc func(ctx context.Context, event *AuditEvent)
+
+// Audit implements the Auditor interface
+func (f AuditorFunc) Audit(ctx context.Context, event *AuditEvent) {
+	f(ctx, event)
+}
+
+// UserIdentifier returns a stable identity (ex: user id, email) of a user extracted by the UserExtractor
+type UserIdentifier func(user any) string
+
+// DefaultUserIdentifier returns the id of users that implement GetId() string (ex: proto messages with an id field)
+func DefaultUserIdentifier(user any) string {
+	if u, ok := user.(interface{ GetId() string }); ok {
+		return u.GetId()
+	}
+	return ""
+}
+
+// WithAuditor sets the auditor that receives an AuditEvent for every authorization decision
+func WithAuditor(auditor Auditor) Opt {
+	return func(o *options) {
+		o.auditor = auditor
+	}
+}
+
+// WithUserIdentifier sets the function used to identify the user in audit events. Defaults to DefaultUserIdentifier
+func WithUserIdentifier(identifier UserIdentifier) Opt {
+	return func(o *options) {
+		o.userIdentifier = identifier
+	}
+}
+
+// WithAuditMetadataKeys sets the metadata keys that are included in audit events.
+// No metadata is audited by default so credentials (ex: authorization headers) aren't logged
+func WithAuditMetadataKeys(keys ...string) Opt {
+	return func(o *options) {
+		o.auditMetadataKeys = append(o.auditMetadataKeys, keys...)
+	}
+}
+
+func (o *options) audit(ctx context.Context, method string, params *RuleExecutionParams, decision *Decision, latency time.Duration, err error, dryRun bool) {
+	if o.auditor == nil {
+		return
+	}
+	event := &AuditEvent{
+		Time:         time.Now(),
+		Method:       method,
+		User:         params.User,
+		IsStream:     params.IsStream,
+		MessageIndex: params.MessageIndex,
+		Decision:     decision,
+		Latency:      latency,
+		Err:          err,
+		DryRun:       dryRun,
+	}
+	if params.Peer != nil {
+		event.Peer = params.Peer.Address
+	} else if p := PeerFromContext(ctx); p != nil {
+		event.Peer = p.Address
+	}
+	identifier := o.userIdentifier
+	if identifier == nil {
+		identifier = DefaultUserIdentifier
+	}
+	if params.User != nil {
+		event.UserID = identifier(params.User)
+	}
+	if len(o.auditMetadataKeys) > 0 && params.Metadata != nil {
+		event.Metadata = metadata.MD{}
+		for _, k := range o.auditMetadataKeys {
+			k = strings.ToLower(k)
+			if v, ok := params.Metadata[k]; ok {
+				event.Metadata[k] = v
+			}
+		}
+	}
+	o.auditor.Audit(ctx, event)
+}
+
+// SlogAuditor is an Auditor that logs audit events with a slog.Logger.
+// Allowed requests are logged at info level, denied requests at warn level and errors at error level
+type SlogAuditor struct {
+	logger *slog.Logger
+}
+
+// NewSlogAuditor returns a new SlogAuditor that logs to the given logger
+func NewSlogAuditor(logger *slog.Logger) *SlogAuditor {
+	return &SlogAuditor{logger: logger}
+}
+
+// NewJSONAuditor returns a new SlogAuditor that writes audit events as JSON lines to the given writer
+func NewJSONAuditor(w io.Writer) *SlogAuditor {
+	return NewSlogAuditor(slog.New(slog.NewJSONHandler(w, nil)))
+}
+
+// Audit implements the Auditor interface
+func (a *SlogAuditor) Audit(ctx context.Context, event *AuditEvent) {
+	attrs := []slog.Attr{
+		slog.String("method", event.Method),
+		slog.String("peer", event.Peer),
+		slog.String("user_id", event.UserID),
+		slog.Bool("is_stream", event.IsStream),
+		slog.Duration("latency", event.Latency),
+	}
+	if event.DryRun {
+		attrs = append(attrs, slog.Bool("dry_run", true))
+	}
+	if event.IsStream {
+		attrs = append(attrs, slog.Int("message_index", event.MessageIndex))
+	}
+	if len(event.Metadata) > 0 {
+		attrs = append(attrs, slog.Any("metadata", map[string][]string(event.Metadata)))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", event.Err.Error()))
+		a.logger.LogAttrs(ctx, slog.LevelError, "authorization error", attrs...)
+		return
+	}
+	attrs = append(attrs,
+		slog.String("effect", string(event.Decision.Effect)),
+		slog.Int("rule_index", event.Decision.RuleIndex),
+		slog.String("expression", event.Decision.Expression),
+		slog.String("engine", event.Decision.Engine),
+	)
+	if event.Decision.RuleName != "" {
+		attrs = append(attrs, slog.String("rule_name", event.Decision.RuleName))
+	}
+	if event.Decision.Reason != "" {
+		attrs = append(attrs, slog.String("reason", event.Decision.Reason))
+	}
+	if event.Decision.Allowed() {
+		a.logger.LogAttrs(ctx, slog.LevelInfo, "authorization allowed", attrs...)
+		return
+	}
+	a.logger.LogAttrs(ctx, slog.LevelWarn, "authorization denied", attrs...)
+}
+
+// FileAuditor is an Auditor that writes audit events as JSON lines to a local file.
+// The file is rotated when it exceeds its max size, keeping up to max backups (path.1 is the most recent)
+type FileAuditor struct {
+	*SlogAuditor
+	w *rotatingFile
+}
+
+// NewFileAuditor returns a new FileAuditor that writes to the file at the given path. The file is rotated when it
+// exceeds maxBytes (0 disables rotation) and at most maxBackups rotated files are kept
+func NewFileAuditor(path string, maxBytes int64, maxBackups int) (*FileAuditor, error) {
+	w := &rotatingFile{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return &FileAuditor{
+		SlogAuditor: NewJSONAuditor(w),
+		w:           w,
+	}, nil
+}
+
+// Close closes the audit file
+func (a *FileAuditor) Close() error {
+	return a.w.Close()
+}
+
+// rotatingFile is an io.Writer that rotates the file at path when it exceeds maxBytes
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("authorizer: failed to open audit file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("authorizer: failed to stat audit file: %w", err)
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, fmt.Errorf("authorizer: audit file is closed")
+	}
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("authorizer: failed to close audit file: %w", err)
+	}
+	r.file = nil
+	if r.maxBackups > 0 {
+		for i := r.maxBackups - 1; i > 0; i-- {
+			// missing backups are expected until the file has been rotated maxBackups times
+			_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return fmt.Errorf("authorizer: failed to rotate audit file: %w", err)
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return fmt.Errorf("authorizer: failed to rotate audit file: %w", err)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,49 @@
+package authorizer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileAuditor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditor, err := NewFileAuditor(path, 300, 2)
+	if err != nil {
+		t.Fatalf("failed to create file auditor: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		auditor.Audit(context.Background(), &AuditEvent{
+			Method:   "/example.ExampleService/RequestMatch",
+			UserID:   "123",
+			Decision: &Decision{Effect: EffectAllow, RuleIndex: 0, Expression: "true", Engine: "cel"},
+		})
+	}
+	if err := auditor.Close(); err != nil {
+		t.Fatalf("failed to close file auditor: %v", err)
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		bits, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("expected audit file %s: %v", name, err)
+		}
+		if len(bits) > 300 {
+			t.Fatalf("expected audit file %s to be rotated at 300 bytes, got %d", name, len(bits))
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(bits)), "\n") {
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("expected json audit record, got %q: %v", line, err)
+			}
+			if record["method"] != "/example.ExampleService/RequestMatch" || record["effect"] != "allow" {
+				t.Fatalf("unexpected audit record: %v", record)
+			}
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected at most 2 backups, got %v", err)
+	}
+}
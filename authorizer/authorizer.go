@@ -2,13 +2,19 @@ package authorizer
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	`github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors`
 	`github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/selector`
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 )
 
 // ExpressionVar is a global variable injected into a Javascript/CEL authorization expression
@@ -17,14 +23,24 @@ type ExpressionVar string
 const (
 	// ExpressionVarRequest is the request object
 	ExpressionVarRequest ExpressionVar = "request"
-	// ExpressionVarMetadata is the metadata object
+	// ExpressionVarMetadata is the metadata object. Multiple values of a key are joined with ","
 	ExpressionVarMetadata ExpressionVar = "metadata"
+	// ExpressionVarMetadataValues is the metadata object with every value of a key (see MetadataValues)
+	ExpressionVarMetadataValues ExpressionVar = "metadata_values"
 	// ExpressionVarUser is the user object
 	ExpressionVarUser ExpressionVar = "user"
 	// ExpressionVarIsStream is true if the grpc handler is a streaming handler
 	ExpressionVarIsStream ExpressionVar = "is_stream"
 	// ExpressionVarMethod is the grpc method
 	ExpressionVarMethod ExpressionVar = "method"
+	// ExpressionVarMessageIndex is the index of the message in the stream when stream messages are authorized individually
+	ExpressionVarMessageIndex ExpressionVar = "message_index"
+	// ExpressionVarPeer is the remote peer of the request (see Peer.Map)
+	ExpressionVarPeer ExpressionVar = "peer"
+	// ExpressionVarDeadline is the deadline of the request, or null if it has no deadline
+	ExpressionVarDeadline ExpressionVar = "deadline"
+	// ExpressionVarNow is the time the request was authorized
+	ExpressionVarNow ExpressionVar = "now"
 )
 
 // RuleExecutionParams is the set of parameters passed to the Authorizer.ExecuteRule function
@@ -37,6 +53,32 @@ type RuleExecutionParams struct {
 	Metadata metadata.MD
 	// IsStream is true if the grpc handler is a streaming handler
 	IsStream bool
+	// MessageIndex is the index of the message in the stream when stream messages are authorized individually
+	MessageIndex int
+	// Peer is the remote peer of the request. The interceptors set it from the context if it is nil
+	Peer *Peer
+	// Deadline is the deadline of the request, or the zero time if it has no deadline
+	Deadline time.Time
+	// Now is the time the request was authorized. The interceptors set it to the current time if it is zero
+	Now time.Time
+}
+
+// MetadataValues returns every value of each metadata key. Values of binary headers (keys ending in "-bin") are returned
+// as []byte and all other values as strings
+func MetadataValues(md metadata.MD) map[string][]any {
+	values := make(map[string][]any, len(md))
+	for k, v := range md {
+		list := make([]any, 0, len(v))
+		for _, value := range v {
+			if strings.HasSuffix(k, "-bin") {
+				list = append(list, []byte(value))
+			} else {
+				list = append(list, value)
+			}
+		}
+		values[k] = list
+	}
+	return values
 }
 
 // UserExtractor is a function that extracts a user from a context so it's attributes can be used in rule expression evaluation
@@ -47,11 +89,12 @@ type ctxKey string
 // DefaultUserExtractorKey is the default key used to extract a user from the context
 var DefaultUserExtractorKey ctxKey = "user"
 
-// DefaultUserExtractor is the default user extractor function that extracts a user from the context using the DefaultUserExtractorKey
+// DefaultUserExtractor is the default user extractor function that extracts a user from the context using the DefaultUserExtractorKey.
+// It returns an Unauthenticated error if the context has no user
 func DefaultUserExtractor(ctx context.Context) (any, error) {
 	user := ctx.Value(DefaultUserExtractorKey)
 	if user == nil {
-		return nil, status.Errorf(codes.PermissionDenied, "authorizer: permission denied")
+		return nil, status.Errorf(codes.Unauthenticated, "authorizer: unauthenticated")
 	}
 	return user, nil
 }
@@ -70,10 +113,131 @@ type Authorizer interface {
 	AuthorizeMethod(ctx context.Context, method string, params *RuleExecutionParams) (allow bool, err error)
 }
 
+// RuleSetLookup is implemented by authorizers that are backed by authorize.RuleSet rules so the interceptors
+// can honor the options of a method's RuleSet (ex: authorize_stream_messages)
+type RuleSetLookup interface {
+	// RuleSet returns the RuleSet of the given method
+	RuleSet(method string) (*authorize.RuleSet, bool)
+}
+
+func lookupRuleSet(authorizer any, method string) *authorize.RuleSet {
+	if l, ok := authorizer.(RuleSetLookup); ok {
+		if rules, ok := l.RuleSet(method); ok {
+			return rules
+		}
+	}
+	return nil
+}
+
 type options struct {
-	userExtractor    UserExtractor
-	whiteListMethods []string
-	selectors        []selector.Matcher
+	userExtractor     UserExtractor
+	whiteListMethods  []string
+	selectors         []selector.Matcher
+	auditor           Auditor
+	userIdentifier    UserIdentifier
+	auditMetadataKeys []string
+	dryRun            bool
+	dryRunHandler     DryRunHandler
+	shadowAuthorizer  DecisionAuthorizer
+	shadowHandler     ShadowHandler
+	shadowTimeout     time.Duration
+	metrics           Metrics
+	tracer            trace.Tracer
+}
+
+// extractUser extracts the user from the context with the user extractor. Extraction errors are audited, and errors
+// that aren't grpc status errors are returned as Unauthenticated errors
+func (o *options) extractUser(ctx context.Context, method string, md metadata.MD, isStream bool) (any, error) {
+	if o.userExtractor == nil {
+		return nil, nil
+	}
+	usr, err := o.userExtractor(ctx)
+	if err != nil {
+		if o.metrics != nil {
+			o.metrics.IncDecision(method, OutcomeUnauthenticated, NoRule)
+		}
+		if _, ok := status.FromError(err); !ok {
+			err = status.Errorf(codes.Unauthenticated, "authorizer: %v", err)
+		}
+		o.audit(ctx, method, &RuleExecutionParams{Metadata: md, IsStream: isStream}, nil, 0, err, false)
+		return nil, err
+	}
+	return usr, nil
+}
+
+// authorize evaluates the method's rules with the authorizer and returns the DenialError of the decision if the request is denied.
+// It is shared by all of the interceptors so every decision is audited, shadowed and dry run the same way
+func (o *options) authorize(ctx context.Context, authorizer DecisionAuthorizer, method string, params *RuleExecutionParams) error {
+	if params.Peer == nil {
+		params.Peer = PeerFromContext(ctx)
+	}
+	if deadline, ok := ctx.Deadline(); ok && params.Deadline.IsZero() {
+		params.Deadline = deadline
+	}
+	start := time.Now()
+	if params.Now.IsZero() {
+		params.Now = start
+	}
+	dryRun := o.isDryRun(authorizer, method)
+	decision, err := TraceDecision(ctx, o.tracer, "authorizer.Authorize", method, params, func(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+		if o.tracer != nil {
+			trace.SpanFromContext(ctx).SetAttributes(AttributeDryRun.Bool(dryRun))
+		}
+		return authorizer.Decide(ctx, method, params)
+	})
+	latency := time.Since(start)
+	o.audit(ctx, method, params, decision, latency, err, dryRun)
+	o.observe(method, decision, latency, err)
+	o.shadow(ctx, method, params, decision)
+	if dryRun {
+		if o.dryRunHandler != nil {
+			o.dryRunHandler(ctx, &DryRunEvent{
+				Method:   method,
+				Params:   params,
+				Decision: decision,
+				Err:      err,
+			})
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !decision.Allowed() {
+		return DenialError(decision)
+	}
+	return nil
+}
+
+// DenialError returns the error of a denied decision. By default, it is a PermissionDenied error with the message
+// "authorizer: permission denied". The Denial of the rule or RuleSet that denied the request can customize the message
+// and status code, and attach an errdetails.ErrorInfo detail
+func DenialError(decision *Decision) error {
+	var denial *authorize.Denial
+	if decision != nil {
+		denial = decision.Denial
+	}
+	code := codes.PermissionDenied
+	if denial.GetCode() != 0 {
+		code = codes.Code(denial.GetCode())
+	}
+	message := "authorizer: permission denied"
+	if denial.GetMessage() != "" {
+		message = denial.GetMessage()
+	}
+	st := status.New(code, message)
+	if denial.GetReason() == "" && denial.GetDomain() == "" && len(denial.GetMetadata()) == 0 {
+		return st.Err()
+	}
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   denial.GetReason(),
+		Domain:   denial.GetDomain(),
+		Metadata: denial.GetMetadata(),
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
 }
 
 // Opt is an option for configuring the interceptor
@@ -103,12 +267,14 @@ func WithSelectors(selectors ...selector.Matcher) Opt {
 }
 
 // UnaryServerInterceptor uses the given authorizer to authorize unary grpc requests.
-// JavascriptAuthorizer/CELAuthorizer are implementations of Authorizer that use javascript/CEL expressions to authorize requests
-func UnaryServerInterceptor(authorizer Authorizer, opts ...Opt) grpc.UnaryServerInterceptor {
+// JavascriptAuthorizer/CELAuthorizer are implementations of Authorizer that use javascript/CEL expressions to authorize requests.
+// Authorizers that don't implement DecisionAuthorizer are adapted with AsDecisionAuthorizer
+func UnaryServerInterceptor(authz Authorizer, opts ...Opt) grpc.UnaryServerInterceptor {
 	o := &options{}
 	for _, opt := range opts {
 		opt(o)
 	}
+	authorizer := AsDecisionAuthorizer(authz)
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
 		if len(o.selectors) > 0 {
 			meta := interceptors.NewServerCallMeta(info.FullMethod, nil, req)
@@ -123,7 +289,7 @@ func UnaryServerInterceptor(authorizer Authorizer, opts ...Opt) grpc.UnaryServer
 	}
 }
 
-func unaryServerInterceptor(authorizer Authorizer, o *options) grpc.UnaryServerInterceptor {
+func unaryServerInterceptor(authorizer DecisionAuthorizer, o *options) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 
 		for _, m := range o.whiteListMethods {
@@ -131,41 +297,34 @@ func unaryServerInterceptor(authorizer Authorizer, o *options) grpc.UnaryServerI
 				return handler(ctx, req)
 			}
 		}
-		var (
-			usr any
-			err error
-		)
-		if o.userExtractor != nil {
-			usr, err = o.userExtractor(ctx)
-			if err != nil {
-				return nil, err
-			}
-		}
 		md, _ := metadata.FromIncomingContext(ctx)
-		authorized, err := authorizer.AuthorizeMethod(ctx, info.FullMethod, &RuleExecutionParams{
+		usr, err := o.extractUser(ctx, info.FullMethod, md, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := o.authorize(ctx, authorizer, info.FullMethod, &RuleExecutionParams{
 			User:     usr,
 			Request:  req,
 			Metadata: md,
-		})
-		if err != nil {
+		}); err != nil {
 			return nil, err
 		}
-		if authorized {
-			return handler(ctx, req)
-		}
-
-		return nil, status.Errorf(codes.PermissionDenied, "authorizer: permission denied")
+		return handler(ctx, req)
 	}
 }
 
 // StreamServerInterceptor uses the given authorizer to authorize streaming grpc requests.
 // JavascriptAuthorizer/CELAuthorizer are implementations of Authorizer that use javascript/CEL expressions to authorize requests
-// the request object in the expression evaluation is nil because it is not available in the context for streaming requests
-func StreamServerInterceptor(authorizer Authorizer, opts ...Opt) grpc.StreamServerInterceptor {
+// the request object in the expression evaluation is nil because it is not available in the context for streaming requests.
+// If the method's RuleSet enables authorize_stream_messages (see RuleSetLookup), the rules are also evaluated against
+// every message received from the client and the stream is aborted on the first denied message.
+// Authorizers that don't implement DecisionAuthorizer are adapted with AsDecisionAuthorizer
+func StreamServerInterceptor(authz Authorizer, opts ...Opt) grpc.StreamServerInterceptor {
 	o := &options{}
 	for _, opt := range opts {
 		opt(o)
 	}
+	authorizer := AsDecisionAuthorizer(authz)
 	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		if len(o.selectors) > 0 {
 			meta := interceptors.NewServerCallMeta(info.FullMethod, info, nil)
@@ -180,51 +339,88 @@ func StreamServerInterceptor(authorizer Authorizer, opts ...Opt) grpc.StreamServ
 	}
 }
 
-func streamServerInterceptor(authorizer Authorizer, o *options) grpc.StreamServerInterceptor {
+func streamServerInterceptor(authorizer DecisionAuthorizer, o *options) grpc.StreamServerInterceptor {
 	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		for _, m := range o.whiteListMethods {
 			if m == info.FullMethod {
 				return handler(srv, ss)
 			}
 		}
-		var (
-			usr any
-			err error
-		)
-		if o.userExtractor != nil {
-			usr, err = o.userExtractor(ss.Context())
-			if err != nil {
-				return err
-			}
-		}
 		md, _ := metadata.FromIncomingContext(ss.Context())
-		authorized, err := authorizer.AuthorizeMethod(ss.Context(), info.FullMethod, &RuleExecutionParams{
+		usr, err := o.extractUser(ss.Context(), info.FullMethod, md, true)
+		if err != nil {
+			return err
+		}
+		// the stream is authorized when it is opened, even if its messages are authorized individually, so handlers
+		// that send before they receive are authorized too
+		if err := o.authorize(ss.Context(), authorizer, info.FullMethod, &RuleExecutionParams{
 			User:     usr,
 			Metadata: md,
 			IsStream: true,
-		})
-		if err != nil {
+		}); err != nil {
 			return err
 		}
-		if authorized {
-			return handler(srv, ss)
+		if info.IsClientStream && lookupRuleSet(authorizer, info.FullMethod).GetAuthorizeStreamMessages() {
+			return handler(srv, &authorizedServerStream{
+				ServerStream: ss,
+				authorizer:   authorizer,
+				opts:         o,
+				method:       info.FullMethod,
+				user:         usr,
+				md:           md,
+			})
 		}
-		return status.Errorf(codes.PermissionDenied, "authorizer: permission denied")
+		return handler(srv, ss)
 	}
 }
 
-// Chain chains multiple authorizers together - if any authorizer returns true, the request is authorized
+// authorizedServerStream authorizes every message received from the client
+type authorizedServerStream struct {
+	grpc.ServerStream
+	authorizer DecisionAuthorizer
+	opts       *options
+	method     string
+	user       any
+	md         metadata.MD
+	index      int
+}
+
+// RecvMsg receives a message from the client and evaluates the method's rules against it
+func (s *authorizedServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	params := &RuleExecutionParams{
+		User:         s.user,
+		Request:      m,
+		Metadata:     s.md,
+		IsStream:     true,
+		MessageIndex: s.index,
+	}
+	s.index++
+	return s.opts.authorize(s.Context(), s.authorizer, s.method, params)
+}
+
+// Chain chains multiple authorizers together - if any authorizer returns true, the request is authorized.
+// The returned Authorizer implements DecisionAuthorizer and reports the decision of the authorizer that allowed the request,
+// or the decision of the last authorizer if none of them did
 func Chain(authz ...Authorizer) Authorizer {
-	return AuthorizeMethodFunc(func(ctx context.Context, method string, params *RuleExecutionParams) (bool, error) {
+	return DecideFunc(func(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+		decision := &Decision{
+			Effect:    EffectDeny,
+			RuleIndex: -1,
+			Reason:    "no authorizer allowed the request",
+		}
 		for _, a := range authz {
-			allow, err := a.AuthorizeMethod(ctx, method, params)
+			d, err := AsDecisionAuthorizer(a).Decide(ctx, method, params)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
-			if allow {
-				return true, nil
+			if d.Allowed() {
+				return d, nil
 			}
+			decision = d
 		}
-		return false, nil
+		return decision, nil
 	})
 }
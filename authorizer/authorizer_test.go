@@ -0,0 +1,120 @@
+package authorizer
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// streamAuthorizer allows admins to open streams and every message whose expression is "true"
+type streamAuthorizer struct {
+	rules map[string]*authorize.RuleSet
+}
+
+func (s *streamAuthorizer) AuthorizeMethod(ctx context.Context, method string, params *RuleExecutionParams) (bool, error) {
+	if params.Request == nil {
+		return params.User == "admin", nil
+	}
+	return params.Request.(*authorize.Rule).GetExpression() == "true", nil
+}
+
+func (s *streamAuthorizer) Decide(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+	decision := &Decision{Effect: EffectDeny, RuleIndex: -1}
+	if allow, _ := s.AuthorizeMethod(ctx, method, params); allow {
+		decision.Effect = EffectAllow
+	}
+	return decision, nil
+}
+
+func (s *streamAuthorizer) RuleSet(method string) (*authorize.RuleSet, bool) {
+	rules, ok := s.rules[method]
+	return rules, ok
+}
+
+// fakeServerStream receives its messages from a list and records the messages sent to the client
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv []*authorize.Rule
+	sent []any
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeServerStream) SendMsg(m any) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func (f *fakeServerStream) RecvMsg(m any) error {
+	next := f.recv[0]
+	f.recv = f.recv[1:]
+	m.(*authorize.Rule).Expression = next.GetExpression()
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	const method = "/example.ExampleService/StreamMatch"
+	authz := &streamAuthorizer{rules: map[string]*authorize.RuleSet{
+		method: {Rules: []*authorize.Rule{{Expression: "true"}}, AuthorizeStreamMessages: true},
+	}}
+	info := &grpc.StreamServerInfo{FullMethod: method, IsClientStream: true, IsServerStream: true}
+	// sendFirst sends a message to the client before it receives one
+	sendFirst := func(srv any, ss grpc.ServerStream) error {
+		if err := ss.SendMsg(&authorize.Rule{Expression: "secret"}); err != nil {
+			return err
+		}
+		return ss.RecvMsg(&authorize.Rule{})
+	}
+	type test struct {
+		name       string
+		user       string
+		recv       []*authorize.Rule
+		expectCode codes.Code
+		expectSent int
+	}
+	tests := []test{
+		{
+			name:       "stream opened by an admin",
+			user:       "admin",
+			recv:       []*authorize.Rule{{Expression: "true"}},
+			expectCode: codes.OK,
+			expectSent: 1,
+		},
+		{
+			name:       "handler sends before it receives",
+			user:       "guest",
+			recv:       []*authorize.Rule{{Expression: "true"}},
+			expectCode: codes.PermissionDenied,
+		},
+		{
+			name:       "denied message",
+			user:       "admin",
+			recv:       []*authorize.Rule{{Expression: "false"}},
+			expectCode: codes.PermissionDenied,
+			expectSent: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := StreamServerInterceptor(authz, WithUserExtractor(func(ctx context.Context) (any, error) {
+				return tt.user, nil
+			}))
+			ss := &fakeServerStream{ctx: context.Background(), recv: tt.recv}
+			err := interceptor(nil, ss, info, sendFirst)
+			if status.Code(err) != tt.expectCode {
+				t.Fatalf("expected code %v, got %v", tt.expectCode, err)
+			}
+			if len(ss.sent) != tt.expectSent {
+				t.Fatalf("expected %d sent messages, got %d", tt.expectSent, len(ss.sent))
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package authorizer
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// CacheOpt is an option for configuring a CachingAuthorizer
+type CacheOpt func(c *CachingAuthorizer)
+
+// WithCacheTTL sets how long decisions are cached. Defaults to 1 minute
+func WithCacheTTL(ttl time.Duration) CacheOpt {
+	return func(c *CachingAuthorizer) {
+		c.ttl = ttl
+	}
+}
+
+// WithCacheSize sets the maximum number of cached decisions. The least recently used decision is evicted when the
+// cache is full. Defaults to 10000
+func WithCacheSize(size int) CacheOpt {
+	return func(c *CachingAuthorizer) {
+		c.size = size
+	}
+}
+
+// WithCacheUserIdentifier sets the function used to identify the user in cache keys. Defaults to DefaultUserIdentifier.
+// Decisions of users without an id are not cached
+func WithCacheUserIdentifier(identifier UserIdentifier) CacheOpt {
+	return func(c *CachingAuthorizer) {
+		c.identify = identifier
+	}
+}
+
+// VersionedPolicy is implemented by authorizers whose policy changes at runtime (ex: ReloadingAuthorizer) so that
+// CachingAuthorizer never serves a decision of a previous version of the policy
+type VersionedPolicy interface {
+	// PolicyVersion returns the version of the current policy. It changes every time the policy is replaced
+	PolicyVersion() uint64
+}
+
+// CachingAuthorizer is an Authorizer that caches the decisions of the wrapped authorizer for methods whose RuleSet
+// declares a cache (see authorize.DecisionCache). Decisions are keyed by the method, the id of the user, the stream
+// message index and the request fields and metadata keys declared in the RuleSet's cache, so the rules of a cached
+// method must not depend on other inputs (ex: peer, now or deadline). Errors are never cached.
+// If the wrapped authorizer implements VersionedPolicy, the cache is purged when its policy changes
+type CachingAuthorizer struct {
+	authorizer Authorizer
+	decider    DecisionAuthorizer
+	ttl        time.Duration
+	size       int
+	identify   UserIdentifier
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	// version is the policy version of the cached decisions
+	version uint64
+}
+
+// cacheEntry is a cached decision
+type cacheEntry struct {
+	key      string
+	decision Decision
+	expires  time.Time
+}
+
+// NewCachingAuthorizer returns a CachingAuthorizer that caches the decisions of the given authorizer. The authorizer must
+// implement RuleSetLookup (ex: the cel and javascript authorizers) - otherwise, no decisions are cached
+func NewCachingAuthorizer(authorizer Authorizer, opts ...CacheOpt) *CachingAuthorizer {
+	c := &CachingAuthorizer{
+		authorizer: authorizer,
+		decider:    AsDecisionAuthorizer(authorizer),
+		ttl:        time.Minute,
+		size:       10000,
+		identify:   DefaultUserIdentifier,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// AuthorizeMethod implements the Authorizer interface
+func (c *CachingAuthorizer) AuthorizeMethod(ctx context.Context, method string, params *RuleExecutionParams) (bool, error) {
+	decision, err := c.Decide(ctx, method, params)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+// Decide implements the DecisionAuthorizer interface. Cached decisions are returned with Cached set to true
+func (c *CachingAuthorizer) Decide(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+	key, ok, err := c.key(method, params)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return c.decider.Decide(ctx, method, params)
+	}
+	if decision, ok := c.get(key); ok {
+		return decision, nil
+	}
+	decision, err := c.decider.Decide(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, decision)
+	return decision, nil
+}
+
+// RuleSet implements the RuleSetLookup interface with the wrapped authorizer
+func (c *CachingAuthorizer) RuleSet(method string) (*authorize.RuleSet, bool) {
+	rules := lookupRuleSet(c.authorizer, method)
+	return rules, rules != nil
+}
+
+// Len returns the number of cached decisions, including expired decisions that haven't been evicted yet
+func (c *CachingAuthorizer) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge removes every cached decision (ex: after the policy of the wrapped authorizer changed)
+func (c *CachingAuthorizer) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+}
+
+// purgeVersion purges the cache if the decisions were cached with a different policy version
+func (c *CachingAuthorizer) purgeVersion(version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version == version {
+		return
+	}
+	c.version = version
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+}
+
+func (c *CachingAuthorizer) get(key string) (*Decision, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	decision := entry.decision
+	decision.Cached = true
+	decision.EvaluationTime = 0
+	return &decision, true
+}
+
+func (c *CachingAuthorizer) set(key string, decision *Decision) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &cacheEntry{key: key, decision: *decision, expires: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// key returns the cache key of the request. ok is false if the method's decisions aren't cacheable
+func (c *CachingAuthorizer) key(method string, params *RuleExecutionParams) (key string, ok bool, err error) {
+	cache := lookupRuleSet(c.authorizer, method).GetCache()
+	if cache == nil {
+		return "", false, nil
+	}
+	var version uint64
+	if v, ok := c.authorizer.(VersionedPolicy); ok {
+		version = v.PolicyVersion()
+		c.purgeVersion(version)
+	}
+	userID := ""
+	if params.User != nil {
+		if userID = c.identify(params.User); userID == "" {
+			return "", false, nil
+		}
+	}
+	// the version is part of the key so a decision of a previous policy that is cached while the cache is purged is never served
+	parts := []string{
+		strconv.FormatUint(version, 10),
+		method,
+		userID,
+		strconv.FormatBool(params.IsStream),
+		strconv.Itoa(params.MessageIndex),
+	}
+	if len(cache.GetRequestFields()) > 0 {
+		msg, isMessage := params.Request.(proto.Message)
+		if !isMessage {
+			return "", false, nil
+		}
+		for _, path := range cache.GetRequestFields() {
+			value, err := requestFieldKey(msg.ProtoReflect(), path)
+			if err != nil {
+				return "", false, err
+			}
+			parts = append(parts, value)
+		}
+	}
+	for _, k := range cache.GetMetadataKeys() {
+		values := params.Metadata.Get(k)
+		quoted := make([]string, 0, len(values))
+		for _, v := range values {
+			quoted = append(quoted, strconv.Quote(v))
+		}
+		parts = append(parts, strings.Join(quoted, ","))
+	}
+	for i, part := range parts {
+		parts[i] = strconv.Quote(part)
+	}
+	return strings.Join(parts, ","), true, nil
+}
+
+// requestFieldKey returns the deterministic encoding of the request field at the given path of proto field names
+func requestFieldKey(msg protoreflect.Message, path string) (string, error) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return "", fmt.Errorf("authorizer: invalid cache request field %q: %s has no field %q", path, msg.Descriptor().FullName(), name)
+		}
+		if i < len(names)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return "", fmt.Errorf("authorizer: invalid cache request field %q: %q is not a message field", path, name)
+			}
+			msg = msg.Get(fd).Message()
+			continue
+		}
+		// the field is copied to an empty message so lists, maps and messages are encoded like scalars
+		field := msg.Type().New()
+		if msg.Has(fd) {
+			field.Set(fd, msg.Get(fd))
+		}
+		bits, err := proto.MarshalOptions{Deterministic: true}.Marshal(field.Interface())
+		if err != nil {
+			return "", fmt.Errorf("authorizer: failed to encode cache request field %q: %w", path, err)
+		}
+		return string(bits), nil
+	}
+	return "", fmt.Errorf("authorizer: invalid cache request field %q", path)
+}
@@ -0,0 +1,152 @@
+package authorizer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// countingAuthorizer counts the requests that reach the staticAuthorizer
+type countingAuthorizer struct {
+	*staticAuthorizer
+	calls int
+}
+
+func (c *countingAuthorizer) AuthorizeMethod(ctx context.Context, method string, params *RuleExecutionParams) (bool, error) {
+	c.calls++
+	return c.staticAuthorizer.AuthorizeMethod(ctx, method, params)
+}
+
+type cacheUser struct {
+	id string
+}
+
+func (u cacheUser) GetId() string {
+	return u.id
+}
+
+func TestCachingAuthorizer(t *testing.T) {
+	const (
+		cached   = "/example.ExampleService/Cached"
+		uncached = "/example.ExampleService/Uncached"
+	)
+	authz := &countingAuthorizer{staticAuthorizer: &staticAuthorizer{rules: map[string]*authorize.RuleSet{
+		cached: {
+			Rules: []*authorize.Rule{{Expression: "true"}},
+			Cache: &authorize.DecisionCache{
+				RequestFields: []string{"expression", "denial.code"},
+				MetadataKeys:  []string{"x-account-id"},
+			},
+		},
+		uncached: {Rules: []*authorize.Rule{{Expression: "true"}}},
+	}}}
+	cache := NewCachingAuthorizer(authz, WithCacheTTL(50*time.Millisecond), WithCacheSize(2))
+	// the request is a proto message so its fields can be part of the cache key
+	request := func(expression string, code uint32) *authorize.Rule {
+		return &authorize.Rule{Expression: expression, Effect: authorize.Effect_EFFECT_DENY, Denial: &authorize.Denial{Code: code}}
+	}
+	type test struct {
+		name         string
+		method       string
+		params       *RuleExecutionParams
+		expectCached bool
+	}
+	tests := []test{
+		{
+			name:   "miss",
+			method: cached,
+			params: &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("a", 1), Metadata: metadata.Pairs("x-account-id", "123")},
+		},
+		{
+			name:         "hit",
+			method:       cached,
+			params:       &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("a", 1), Metadata: metadata.Pairs("x-account-id", "123", "x-other", "ignored")},
+			expectCached: true,
+		},
+		{
+			name:         "undeclared request fields are not part of the key",
+			method:       cached,
+			params:       &RuleExecutionParams{User: cacheUser{id: "1"}, Request: &authorize.Rule{Expression: "a", Denial: &authorize.Denial{Code: 1, Message: "ignored"}}, Metadata: metadata.Pairs("x-account-id", "123")},
+			expectCached: true,
+		},
+		{
+			name:   "different user",
+			method: cached,
+			params: &RuleExecutionParams{User: cacheUser{id: "2"}, Request: request("a", 1), Metadata: metadata.Pairs("x-account-id", "123")},
+		},
+		{
+			name:   "different request field",
+			method: cached,
+			params: &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("b", 1), Metadata: metadata.Pairs("x-account-id", "123")},
+		},
+		{
+			name:   "different nested request field",
+			method: cached,
+			params: &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("a", 2), Metadata: metadata.Pairs("x-account-id", "123")},
+		},
+		{
+			name:   "stream message",
+			method: cached,
+			params: &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("a", 1), Metadata: metadata.Pairs("x-account-id", "123"), IsStream: true},
+		},
+		{
+			name:   "different stream message index",
+			method: cached,
+			params: &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("a", 1), Metadata: metadata.Pairs("x-account-id", "123"), IsStream: true, MessageIndex: 1},
+		},
+		{
+			name:   "different metadata",
+			method: cached,
+			params: &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("a", 1), Metadata: metadata.Pairs("x-account-id", "456")},
+		},
+		{
+			name:   "user without id",
+			method: cached,
+			params: &RuleExecutionParams{User: cacheUser{}, Request: request("a", 1), Metadata: metadata.Pairs("x-account-id", "123")},
+		},
+		{
+			name:   "method without cache",
+			method: uncached,
+			params: &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("a", 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := authz.calls
+			decision, err := cache.Decide(context.Background(), tt.method, tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !decision.Allowed() {
+				t.Fatalf("expected allow decision, got %+v", decision)
+			}
+			if decision.Cached != tt.expectCached || (authz.calls == calls) != tt.expectCached {
+				t.Fatalf("expected cached=%v, got cached=%v with %d authorizer calls", tt.expectCached, decision.Cached, authz.calls-calls)
+			}
+		})
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected the cache to be bounded to 2 decisions, got %d", cache.Len())
+	}
+	// the most recently used decision is still cached until it expires
+	params := &RuleExecutionParams{User: cacheUser{id: "1"}, Request: request("a", 1), Metadata: metadata.Pairs("x-account-id", "456")}
+	if decision, _ := cache.Decide(context.Background(), cached, params); !decision.Cached {
+		t.Fatalf("expected cached decision")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if decision, _ := cache.Decide(context.Background(), cached, params); decision.Cached {
+		t.Fatalf("expected expired decision to be evaluated")
+	}
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Fatalf("expected empty cache after purge, got %d", cache.Len())
+	}
+	authz.rules[cached].Cache.RequestFields = []string{"expression.invalid"}
+	if _, err := cache.Decide(context.Background(), cached, params); err == nil {
+		t.Fatalf("expected invalid cache request field error")
+	}
+}
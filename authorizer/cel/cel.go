@@ -2,18 +2,30 @@ package cel
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
 	"github.com/mitchellh/mapstructure"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 
-	"github.com/autom8ter/proto/gen/authorize"
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 
 	"github.com/autom8ter/protoc-gen-authorize/authorizer"
 )
 
+// Engine is the name of the CEL expression engine reported in authorization decisions
+const Engine = "cel"
+
 // Opt is a functional option for configuring a CelAuthorizer
 type Opt func(*CelAuthorizer)
 
@@ -25,113 +37,456 @@ func WithMacros(macros ...cel.Macro) Opt {
 	}
 }
 
+// WithEnvOptions sets additional cel env options (ex: cel.Variable, cel.Lib or ext.Strings()) that are used to compile every expression.
+// Please note that the built-in authorization function library (see Library) is already included
+func WithEnvOptions(opts ...cel.EnvOption) Opt {
+	return func(c *CelAuthorizer) {
+		c.envOptions = append(c.envOptions, opts...)
+	}
+}
+
+// WithFunction declares an additional function, with its overloads and bindings (ex: cel.Overload), that will be
+// available to the cel vm
+func WithFunction(name string, opts ...cel.FunctionOpt) Opt {
+	return WithEnvOptions(cel.Function(name, opts...))
+}
+
+// WithMissingRulesPolicy sets how requests to methods that have no rules are authorized.
+// Defaults to authorizer.MissingRulesAllowIfServiceAnnotated
+func WithMissingRulesPolicy(policy authorizer.MissingRulesPolicy) Opt {
+	return func(c *CelAuthorizer) {
+		c.missingRules = policy
+	}
+}
+
+// WithTracerProvider sets the tracer provider used to record a span for every decision, with a child span for every
+// rule expression that is evaluated
+func WithTracerProvider(provider trace.TracerProvider) Opt {
+	return func(c *CelAuthorizer) {
+		c.tracer = provider.Tracer(authorizer.TracerName)
+	}
+}
+
+// WithProtoMessages enables protobuf-native evaluation of expressions. Instead of decoding the request and user into
+// maps keyed by go field names, proto messages are bound to the request/user variables as is, so expressions reference
+// fields by their proto names (ex: request.account_id) and can use has(), enums and well-known types (ex: timestamps) natively.
+// The request variable is declared with the input message type of the method (looked up in the global proto registry)
+// and is null when a stream is opened. The given message types are registered with the cel vm.
+func WithProtoMessages(types ...proto.Message) Opt {
+	return func(c *CelAuthorizer) {
+		c.protoMessages = true
+		c.types = append(c.types, types...)
+	}
+}
+
+// WithUserMessage declares the user variable as the given proto message type. It implies WithProtoMessages
+func WithUserMessage(user proto.Message) Opt {
+	return func(c *CelAuthorizer) {
+		c.protoMessages = true
+		c.userMessage = user
+	}
+}
+
+// WithCostLimit sets the maximum runtime cost of evaluating an expression. Rules may override it with their cost_limit.
+// Defaults to no limit
+func WithCostLimit(limit uint64) Opt {
+	return func(c *CelAuthorizer) {
+		c.costLimit = limit
+	}
+}
+
+// WithInterruptCheckFrequency sets how many comprehension iterations (ex: all, exists, map) are evaluated between checks
+// for interruption by the request context or the max duration. Defaults to 100
+func WithInterruptCheckFrequency(frequency uint) Opt {
+	return func(c *CelAuthorizer) {
+		c.interruptCheckFrequency = frequency
+	}
+}
+
+// WithLazyCompilation defers the compilation of rule expressions to the first request of their method instead of
+// compiling every expression in NewCelAuthorizer. Invalid expressions are then only reported by Validate or at request time
+func WithLazyCompilation() Opt {
+	return func(c *CelAuthorizer) {
+		c.lazy = true
+	}
+}
+
+// WithMaxDuration sets the maximum time the rule expressions of a request may run before they are interrupted.
+// Expressions are always interrupted when the context of the request is done (ex: its deadline is exceeded).
+// Defaults to no limit
+func WithMaxDuration(maxDuration time.Duration) Opt {
+	return func(c *CelAuthorizer) {
+		c.maxDuration = maxDuration
+	}
+}
+
 // CelAuthorizer is a Common Expression Language vm that uses CEL expressions to authorize grpc requests
 type CelAuthorizer struct {
 	rules          map[string]*authorize.RuleSet
 	cachedPrograms sync.Map
+	cachedEnvs     sync.Map
 	macros         []cel.Macro
+	envOptions     []cel.EnvOption
+	protoMessages  bool
+	types          []proto.Message
+	userMessage    proto.Message
+	missingRules   authorizer.MissingRulesPolicy
+	tracer         trace.Tracer
+	costLimit      uint64
+	maxDuration    time.Duration
+	lazy           bool
+	// interruptCheckFrequency is the number of comprehension iterations between interrupt checks
+	interruptCheckFrequency uint
 }
 
 // NewCelAuthorizer returns a new CelAuthorizer. The rules map is a map of method names to RuleSets. The RuleSets are used to
-// authorize the method. The RuleSets are evaluated in order and the first rule that evaluates to true will authorize
-// the request. The mapping can be generated with the protoc-gen-authorize plugin.
+// authorize the method. The rules of a RuleSet are combined using the RuleSet's combining algorithm - by default,
+// the effect of the first rule that evaluates to true is applied to the request. The mapping can be generated with the protoc-gen-authorize plugin.
+// Every rule expression is compiled up front and an error describing each expression that fails to compile is returned,
+// unless WithLazyCompilation is set.
 func NewCelAuthorizer(rules map[string]*authorize.RuleSet, opts ...Opt) (*CelAuthorizer, error) {
 	c := &CelAuthorizer{
 		rules:          rules,
 		cachedPrograms: sync.Map{},
+		cachedEnvs:     sync.Map{},
+
+		interruptCheckFrequency: 100,
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
 	c.macros = append(c.macros, cel.StandardMacros...)
+	if !c.lazy {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadCelAuthorizer loads a YAML or JSON policy document of method names to RuleSets (see authorizer.ParsePolicy) and returns
+// a new CelAuthorizer for it. The policy is rejected if any of its expressions fail to compile
+func LoadCelAuthorizer(path string, opts ...Opt) (*CelAuthorizer, error) {
+	rules, err := authorizer.LoadPolicy(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewCelAuthorizer(rules, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if c.lazy {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	return c, nil
 }
 
+// Factory returns an authorizer.AuthorizerFactory that creates a CelAuthorizer with the given options, for example
+// to hot reload a policy with authorizer.NewReloadingAuthorizer
+func Factory(opts ...Opt) authorizer.AuthorizerFactory {
+	return func(rules map[string]*authorize.RuleSet) (authorizer.Authorizer, error) {
+		return NewCelAuthorizer(rules, opts...)
+	}
+}
+
+// Validate compiles the expressions of every method's rules and returns an error describing each expression that fails to compile
+func (c *CelAuthorizer) Validate() error {
+	methods := make([]string, 0, len(c.rules))
+	for method := range c.rules {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	var errs []error
+	for _, method := range methods {
+		for i, rule := range c.rules[method].GetRules() {
+			if _, err := c.getProgram(method, rule); err != nil {
+				errs = append(errs, fmt.Errorf("%s: rule %d: %w", method, i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RuleSet returns the RuleSet of the given method
+func (c *CelAuthorizer) RuleSet(method string) (*authorize.RuleSet, bool) {
+	rules, ok := c.rules[method]
+	return rules, ok
+}
+
 // AuthorizeMethod authorizes a gRPC method the RuleExecutionParams and returns a boolean representing whether the
 // request is authorized or not.
 func (c *CelAuthorizer) AuthorizeMethod(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (bool, error) {
+	decision, err := c.Decide(ctx, method, params)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+// Decide authorizes a gRPC method the RuleExecutionParams and returns a Decision describing which rule
+// authorized the request, or why it was denied.
+func (c *CelAuthorizer) Decide(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (*authorizer.Decision, error) {
+	return authorizer.TraceDecision(ctx, c.tracer, Engine+".Decide", method, params, c.decide)
+}
+
+func (c *CelAuthorizer) decide(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (*authorizer.Decision, error) {
+	start := time.Now()
+	decision := &authorizer.Decision{
+		Effect:    authorizer.EffectDeny,
+		RuleIndex: -1,
+		Engine:    Engine,
+	}
+	defer func() {
+		decision.EvaluationTime = time.Since(start)
+	}()
 	rules, ok := c.rules[method]
 	if !ok {
-		svc := strings.Split(method, "/")[1]
-		fmt.Println(svc)
-		for k, _ := range c.rules {
-			if strings.HasPrefix(k, "/"+svc) {
-				return true, nil
-			}
+		if err := authorizer.DecideMissingRules(c.missingRules, c.rules, method, decision); err != nil {
+			return nil, err
 		}
-		return false, nil
+		return decision, nil
 	}
-	if len(rules.Rules) == 1 && rules.Rules[0].Expression == "*" {
-		return true, nil
+	if len(rules.Rules) == 1 && rules.Rules[0].Expression == authorizer.WildcardExpression {
+		if err := authorizer.EvaluateRuleSet(rules, decision, nil); err != nil {
+			return nil, err
+		}
+		return decision, nil
 	}
-	programs, err := c.getMethodPrograms(rules)
+	programs, err := c.getMethodPrograms(method, rules)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	var (
 		metaMap = map[string]string{}
-		request = map[string]interface{}{}
-		user    = map[string]interface{}{}
 	)
 	for k, v := range params.Metadata {
 		metaMap[k] = strings.Join(v, ",")
 	}
-	if err := mapstructure.Decode(params.Request, &request); err != nil {
-		return false, fmt.Errorf("authorizer: failed to decode request: %v", err.Error())
+	var input protoreflect.MessageDescriptor
+	if c.protoMessages {
+		input = methodInput(method)
 	}
-	if err := mapstructure.Decode(params.User, &user); err != nil {
-		return false, fmt.Errorf("authorizer: failed to decode user: %v", err.Error())
+	// the request is null when a stream is opened
+	var request any = types.NullValue
+	if params.Request != nil {
+		request, err = c.bindValue(params.Request, input)
+		if err != nil {
+			return nil, fmt.Errorf("authorizer: failed to decode request: %v", err.Error())
+		}
 	}
-
-	for _, program := range programs {
-		v, _, err := program.Eval(map[string]interface{}{
-			string(authorizer.ExpressionVarMetadata): metaMap,
-			string(authorizer.ExpressionVarRequest):  request,
-			string(authorizer.ExpressionVarUser):     user,
-			string(authorizer.ExpressionVarIsStream): params.IsStream,
-			string(authorizer.ExpressionVarMethod):   method,
-		})
+	var user any
+	if c.userMessage != nil {
+		user, err = c.bindValue(params.User, c.userMessage.ProtoReflect().Descriptor())
+	} else {
+		user, err = c.bindValue(params.User, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("authorizer: failed to decode user: %v", err.Error())
+	}
+	now := params.Now
+	if now.IsZero() {
+		now = start
+	}
+	var deadline any
+	if !params.Deadline.IsZero() {
+		deadline = params.Deadline
+	}
+	vars := map[string]interface{}{
+		string(authorizer.ExpressionVarMetadata):       metaMap,
+		string(authorizer.ExpressionVarMetadataValues): authorizer.MetadataValues(params.Metadata),
+		string(authorizer.ExpressionVarRequest):        request,
+		string(authorizer.ExpressionVarUser):           user,
+		string(authorizer.ExpressionVarIsStream):       params.IsStream,
+		string(authorizer.ExpressionVarMethod):         method,
+		string(authorizer.ExpressionVarMessageIndex):   params.MessageIndex,
+		string(authorizer.ExpressionVarPeer):           params.Peer.Map(),
+		string(authorizer.ExpressionVarDeadline):       deadline,
+		string(authorizer.ExpressionVarNow):            now,
+	}
+	if c.maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.maxDuration)
+		defer cancel()
+	}
+	if err := authorizer.EvaluateRuleSet(rules, decision, authorizer.TraceRuleEvaluator(ctx, c.tracer, Engine+".EvaluateRule", rules, func(i int) (bool, error) {
+		v, _, err := programs[i].ContextEval(ctx, vars)
 		if err != nil {
+			if ctx.Err() != nil {
+				return false, fmt.Errorf("authorizer: expression evaluation interrupted: %w", ctx.Err())
+			}
 			return false, fmt.Errorf("authorizer: failed to run expression: %v", err.Error())
 		}
 		pass, ok := v.Value().(bool)
 		if !ok {
 			return false, fmt.Errorf("authorizer: expression did not return a boolean")
 		}
-		if pass {
-			return true, nil
+		return pass, nil
+	})); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+// bindValue returns the value bound to the request/user variable. Proto messages are bound as is when WithProtoMessages
+// is enabled (an empty message of the declared type is bound if the value is nil), otherwise the value is decoded into
+// a map keyed by go field names
+func (c *CelAuthorizer) bindValue(value any, desc protoreflect.MessageDescriptor) (any, error) {
+	if c.protoMessages {
+		if msg, ok := value.(proto.Message); ok {
+			return msg, nil
+		}
+		if value == nil && desc != nil {
+			if mt, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName()); err == nil {
+				return mt.New().Interface(), nil
+			}
+		}
+	}
+	decoded := map[string]interface{}{}
+	if err := mapstructure.Decode(value, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// methodInput returns the input message descriptor of a grpc method (ex: /pkg.Service/Method) from the global proto registry
+func methodInput(method string) protoreflect.MessageDescriptor {
+	parts := strings.Split(strings.TrimPrefix(method, "/"), "/")
+	if len(parts) != 2 {
+		return nil
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(parts[0]))
+	if err != nil {
+		return nil
+	}
+	svc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	m := svc.Methods().ByName(protoreflect.Name(parts[1]))
+	if m == nil {
+		return nil
+	}
+	return m.Input()
+}
+
+// MapType is the type of the user variable when it is decoded into a map keyed by go field names. The decoded request
+// is declared as dyn instead, since it is null when a stream is opened
+var MapType = cel.MapType(cel.StringType, cel.DynType)
+
+// Variables returns the declarations of the variables injected into expressions by the CelAuthorizer
+// with the given request and user types. The protoc-gen-authorize plugin uses them to type check expressions.
+func Variables(request, user *cel.Type) []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Variable(string(authorizer.ExpressionVarMetadata), cel.MapType(cel.StringType, cel.StringType)),
+		// the values of binary headers are bytes and all other values are strings
+		cel.Variable(string(authorizer.ExpressionVarMetadataValues), cel.MapType(cel.StringType, cel.ListType(cel.DynType))),
+		cel.Variable(string(authorizer.ExpressionVarRequest), request),
+		cel.Variable(string(authorizer.ExpressionVarUser), user),
+		cel.Variable(string(authorizer.ExpressionVarIsStream), cel.BoolType),
+		cel.Variable(string(authorizer.ExpressionVarMessageIndex), cel.IntType),
+		cel.Variable(string(authorizer.ExpressionVarMethod), cel.StringType),
+		cel.Variable(string(authorizer.ExpressionVarPeer), MapType),
+		// deadline is a timestamp, or null if the request has no deadline
+		cel.Variable(string(authorizer.ExpressionVarDeadline), cel.DynType),
+		cel.Variable(string(authorizer.ExpressionVarNow), cel.TimestampType),
+	}
+}
+
+// getEnv returns the cel env used to compile the expressions of a method. Without WithProtoMessages, the request/user
+// variables are maps and every method shares the same env. Otherwise, the env is specific to the method's input message type
+func (c *CelAuthorizer) getEnv(method string) (*cel.Env, string, error) {
+	var (
+		key   string
+		input protoreflect.MessageDescriptor
+	)
+	if c.protoMessages {
+		input = methodInput(method)
+		if input != nil {
+			key = string(input.FullName())
+		}
+	}
+	if env, ok := c.cachedEnvs.Load(key); ok {
+		return env.(*cel.Env), key, nil
+	}
+	opts := []cel.EnvOption{
+		cel.Macros(c.macros...),
+		Library(),
+	}
+	if !c.protoMessages {
+		// the request is dyn rather than a map so that expressions can check if it is null
+		opts = append(opts, Variables(cel.DynType, MapType)...)
+	} else {
+		var (
+			request = cel.DynType
+			user    = cel.DynType
+		)
+		if input != nil {
+			opts = append(opts, cel.TypeDescs(input.ParentFile()))
+			request = cel.ObjectType(string(input.FullName()))
+		}
+		if c.userMessage != nil {
+			opts = append(opts, cel.Types(c.userMessage))
+			user = cel.ObjectType(string(c.userMessage.ProtoReflect().Descriptor().FullName()))
+		}
+		for _, t := range c.types {
+			opts = append(opts, cel.Types(t))
 		}
+		opts = append(opts, Variables(request, user)...)
+	}
+	env, err := cel.NewEnv(append(opts, c.envOptions...)...)
+	if err != nil {
+		return nil, "", fmt.Errorf("authorizer: failed to create cel env: %v", err.Error())
 	}
-	return false, nil
+	c.cachedEnvs.Store(key, env)
+	return env, key, nil
 }
 
-func (c *CelAuthorizer) getMethodPrograms(rules *authorize.RuleSet) ([]cel.Program, error) {
+func (c *CelAuthorizer) getMethodPrograms(method string, rules *authorize.RuleSet) ([]cel.Program, error) {
 	var programs []cel.Program
 	for _, rule := range rules.Rules {
-		program, ok := c.cachedPrograms.Load(rule.Expression)
-		if !ok {
-			vm, err := cel.NewEnv(
-				cel.Variable(string(authorizer.ExpressionVarMetadata), cel.MapType(cel.StringType, cel.StringType)),
-				cel.Variable(string(authorizer.ExpressionVarRequest), cel.MapType(cel.StringType, cel.DynType)),
-				cel.Variable(string(authorizer.ExpressionVarUser), cel.MapType(cel.StringType, cel.DynType)),
-				cel.Variable(string(authorizer.ExpressionVarIsStream), cel.BoolType),
-				cel.Macros(c.macros...),
-			)
-			if err != nil {
-				return nil, fmt.Errorf("authorizer: failed to create cel env: %v", err.Error())
-			}
-			parsed, issues := vm.Parse(rule.Expression)
-			if issues != nil && issues.Err() != nil {
-				return nil, fmt.Errorf("authorizer: failed to parse expression: %v", issues.Err().Error())
-			}
-			program, err = vm.Program(parsed)
-			if err != nil {
-				return nil, fmt.Errorf("authorizer: failed to compile expression: %v", err.Error())
-			}
-			c.cachedPrograms.Store(rule.Expression, program)
+		program, err := c.getProgram(method, rule)
+		if err != nil {
+			return nil, err
 		}
-		programs = append(programs, program.(cel.Program))
+		programs = append(programs, program)
 	}
 	return programs, nil
 }
+
+// getProgram returns the compiled program of the rule's expression, or nil for the wildcard expression
+func (c *CelAuthorizer) getProgram(method string, rule *authorize.Rule) (cel.Program, error) {
+	if rule.Expression == authorizer.WildcardExpression {
+		return nil, nil
+	}
+	vm, envKey, err := c.getEnv(method)
+	if err != nil {
+		return nil, err
+	}
+	costLimit := rule.GetCostLimit()
+	if costLimit == 0 {
+		costLimit = c.costLimit
+	}
+	// programs compiled against a proto-native env are specific to the request type
+	programKey := envKey + ":" + strconv.FormatUint(costLimit, 10) + ":" + rule.Expression
+	if program, ok := c.cachedPrograms.Load(programKey); ok {
+		return program.(cel.Program), nil
+	}
+	// expressions are type checked so that unknown functions and mismatched operands fail at compile time
+	ast, issues := vm.Compile(rule.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("authorizer: failed to compile expression: %v", issues.Err().Error())
+	}
+	opts := []cel.ProgramOption{cel.InterruptCheckFrequency(c.interruptCheckFrequency)}
+	if costLimit > 0 {
+		opts = append(opts, cel.CostLimit(costLimit))
+	}
+	program, err := vm.Program(ast, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("authorizer: failed to compile expression: %v", err.Error())
+	}
+	c.cachedPrograms.Store(programKey, program)
+	return program, nil
+}
@@ -2,12 +2,27 @@ package cel_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/autom8ter/proto/gen/authorize"
+	googlecel "github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 
 	"github.com/autom8ter/protoc-gen-authorize/authorizer"
 	"github.com/autom8ter/protoc-gen-authorize/authorizer/cel"
+	"github.com/autom8ter/protoc-gen-authorize/example/gen/example"
 )
 
 type fixture struct {
@@ -282,6 +297,167 @@ var fixtures = []fixture{
 		},
 		expectAllow: true,
 	},
+	{
+		name:   "first applicable deny rule 10 (deny)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles:       []string{"admin"},
+				IsSuperUser: true,
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+					{
+						Expression: "'admin' in user.Roles",
+					},
+				},
+			},
+		},
+		expectAllow: false,
+	},
+	{
+		name:   "deny overrides rule 11 (deny)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles:       []string{"admin"},
+				IsSuperUser: true,
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "'admin' in user.Roles",
+					},
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+				},
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+			},
+		},
+		expectAllow: false,
+	},
+	{
+		name:   "deny overrides rule 12 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles: []string{"admin"},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "'admin' in user.Roles",
+					},
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+				},
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "permit overrides rule 13 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles:       []string{"admin"},
+				IsSuperUser: true,
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+					{
+						Expression: "'admin' in user.Roles",
+					},
+				},
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_PERMIT_OVERRIDES,
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "wildcard fallback rule 14 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles: []string{"guest"},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+					{
+						Expression: "*",
+					},
+				},
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "metadata values rule 15 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			Metadata: map[string][]string{
+				"x-role":    {"user", "admin"},
+				"x-tags":    {"a,b", "c"},
+				"trace-bin": {string([]byte{1, 2})},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "'admin' in metadata_values['x-role'] && 'a,b' in metadata_values['x-tags'] && size(metadata_values['x-tags']) == 2 && metadata_values['trace-bin'][0] == b'\\x01\\x02'",
+					},
+				},
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "metadata values rule 16 (deny)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			Metadata: map[string][]string{
+				"x-role": {"user", "admin"},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "metadata['x-role'] == 'admin' && 'admin' in metadata_values['x-role']",
+					},
+				},
+			},
+		},
+		expectAllow: false,
+	},
 }
 
 func TestCelAuthorizer_AuthorizeMethod(t *testing.T) {
@@ -325,6 +501,524 @@ func TestCelAuthorizer_AuthorizeMethod(t *testing.T) {
 	}
 }
 
+func TestCelAuthorizer_Decide(t *testing.T) {
+	authz, err := cel.NewCelAuthorizer(map[string]*authorize.RuleSet{
+		"testing": {
+			Rules: []*authorize.Rule{
+				{
+					Expression: "user.IsSuperUser",
+				},
+				{
+					Name:       "admins",
+					Expression: "'admin' in user.Roles",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decision, err := authz.Decide(context.Background(), "testing", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow")
+	}
+	if decision.RuleIndex != 1 || decision.RuleName != "admins" || decision.RulesEvaluated != 2 || decision.Engine != cel.Engine {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+	decision, err = authz.Decide(context.Background(), "testing", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"guest"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decision.Allowed() {
+		t.Fatalf("expected deny")
+	}
+	if decision.RuleIndex != -1 || decision.Reason == "" {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+}
+
+func TestCelAuthorizer_ProtoMessages(t *testing.T) {
+	authz, err := cel.NewCelAuthorizer(map[string]*authorize.RuleSet{
+		example.ExampleService_RequestMatch_FullMethodName: {
+			Rules: []*authorize.Rule{
+				{
+					Expression: "has(request.account_id) && request.account_id in user.account_ids && 'admin' in user.roles",
+				},
+			},
+		},
+	}, cel.WithUserMessage(&example.User{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user := &example.User{
+		AccountIds: []string{"1", "2"},
+		Roles:      []string{"admin"},
+	}
+	for _, fix := range []struct {
+		request     *example.Request
+		expectAllow bool
+	}{
+		{request: &example.Request{AccountId: "1"}, expectAllow: true},
+		{request: &example.Request{AccountId: "3"}, expectAllow: false},
+		{request: &example.Request{}, expectAllow: false},
+	} {
+		allow, err := authz.AuthorizeMethod(context.Background(), example.ExampleService_RequestMatch_FullMethodName, &authorizer.RuleExecutionParams{
+			User:    user,
+			Request: fix.request,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if allow != fix.expectAllow {
+			t.Fatalf("expected allow=%v for request %v", fix.expectAllow, fix.request)
+		}
+	}
+}
+
+func TestCelAuthorizer_StreamOpen(t *testing.T) {
+	type test struct {
+		name       string
+		expression string
+		opts       []cel.Opt
+	}
+	tests := []test{
+		{
+			name:       "maps",
+			expression: "request == null ? size(user.AccountIds) > 0 : request.AccountId in user.AccountIds",
+		},
+		{
+			name:       "proto messages",
+			expression: "request == null ? size(user.account_ids) > 0 : request.account_id in user.account_ids",
+			opts:       []cel.Opt{cel.WithUserMessage(&example.User{})},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authz, err := cel.NewCelAuthorizer(map[string]*authorize.RuleSet{
+				example.ExampleService_StreamMatch_FullMethodName: {
+					Rules:                   []*authorize.Rule{{Expression: tt.expression}},
+					AuthorizeStreamMessages: true,
+				},
+			}, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, fix := range []struct {
+				user        *example.User
+				request     *example.Request
+				expectAllow bool
+			}{
+				// the request is null when the stream is opened
+				{user: &example.User{AccountIds: []string{"1"}}, expectAllow: true},
+				{user: &example.User{}, expectAllow: false},
+				{user: &example.User{AccountIds: []string{"1"}}, request: &example.Request{AccountId: "1"}, expectAllow: true},
+				{user: &example.User{AccountIds: []string{"1"}}, request: &example.Request{AccountId: "2"}, expectAllow: false},
+			} {
+				params := &authorizer.RuleExecutionParams{User: fix.user, IsStream: true}
+				if fix.request != nil {
+					params.Request = fix.request
+				}
+				allow, err := authz.AuthorizeMethod(context.Background(), example.ExampleService_StreamMatch_FullMethodName, params)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if allow != fix.expectAllow {
+					t.Fatalf("expected allow=%v for user %v and request %v", fix.expectAllow, fix.user, fix.request)
+				}
+			}
+		})
+	}
+}
+
+func TestCelAuthorizer_MissingRules(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "false"}},
+		},
+	}
+	type test struct {
+		policy      authorizer.MissingRulesPolicy
+		method      string
+		expectError bool
+		expectAllow bool
+	}
+	tests := []test{
+		{policy: "", method: "/example.ExampleService/MetadataMatch", expectAllow: true},
+		{policy: "", method: "/example.ExampleServiceV2/MetadataMatch", expectAllow: false},
+		{policy: authorizer.MissingRulesAllowIfServiceAnnotated, method: "/other.OtherService/Method", expectAllow: false},
+		{policy: authorizer.MissingRulesAllowIfServiceAnnotated, method: "missing", expectAllow: false},
+		{policy: authorizer.MissingRulesDeny, method: "/example.ExampleService/MetadataMatch", expectAllow: false},
+		{policy: authorizer.MissingRulesAllow, method: "/other.OtherService/Method", expectAllow: true},
+		{policy: authorizer.MissingRulesError, method: "/example.ExampleService/MetadataMatch", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.policy, tt.method), func(t *testing.T) {
+			authz, err := cel.NewCelAuthorizer(rules, cel.WithMissingRulesPolicy(tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			decision, err := authz.Decide(context.Background(), tt.method, &authorizer.RuleExecutionParams{})
+			if tt.expectError {
+				if !errors.Is(err, authorizer.ErrMissingRules) {
+					t.Fatalf("expected missing rules error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decision.Allowed() != tt.expectAllow {
+				t.Fatalf("expected allow=%v, got %+v", tt.expectAllow, decision)
+			}
+		})
+	}
+}
+
+func TestCelAuthorizer_RequestInfo(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "method.startsWith('/example.') && peer.ip == '127.0.0.1' && peer.tls == null && deadline != null && deadline > now && now > timestamp('2020-01-01T00:00:00Z')"}},
+		},
+		"/example.ExampleService/MetadataMatch": {
+			Rules: []*authorize.Rule{{Expression: "deadline == null && peer.address == ''"}},
+		},
+	}
+	for _, opts := range [][]cel.Opt{nil, {cel.WithProtoMessages()}} {
+		authz, err := cel.NewCelAuthorizer(rules, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		decision, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+			Request:  &example.Request{},
+			Peer:     authorizer.NewPeer("127.0.0.1:52342"),
+			Deadline: time.Now().Add(time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !decision.Allowed() {
+			t.Fatalf("expected allow: %+v", decision)
+		}
+		// requests without a peer or deadline
+		decision, err = authz.Decide(context.Background(), "/example.ExampleService/MetadataMatch", &authorizer.RuleExecutionParams{
+			Request: &example.Request{},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !decision.Allowed() {
+			t.Fatalf("expected allow: %+v", decision)
+		}
+	}
+}
+
+func TestLoadCelAuthorizer(t *testing.T) {
+	dir := t.TempDir()
+	policy := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(policy, []byte(`
+/example.ExampleService/RequestMatch:
+  algorithm: COMBINING_ALGORITHM_DENY_OVERRIDES
+  rules:
+    - expression: "user.IsSuperUser"
+      effect: EFFECT_DENY
+    - expression: "'admin' in user.Roles"
+`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authz, err := cel.LoadCelAuthorizer(policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decision, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() || decision.RuleIndex != 1 {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{
+		"/example.ExampleService/RequestMatch": {"rules": [{"expression": "'admin' in user.Roles &&"}]}
+	}`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := cel.LoadCelAuthorizer(invalid); err == nil || !strings.Contains(err.Error(), "/example.ExampleService/RequestMatch") {
+		t.Fatalf("expected compile error for method, got %v", err)
+	}
+}
+
+func TestCelAuthorizer_Validate(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "'admin' in user.Roles"}, {Expression: "user.Roles.exists(r, r =="}},
+		},
+		"/example.ExampleService/MetadataMatch": {
+			Rules: []*authorize.Rule{{Expression: "request.AccountId =="}},
+		},
+		"/example.ExampleService/AllowAll": {
+			Rules: []*authorize.Rule{{Expression: authorizer.WildcardExpression}},
+		},
+	}
+	_, err := cel.NewCelAuthorizer(rules)
+	if err == nil {
+		t.Fatalf("expected compile error")
+	}
+	// every invalid expression is reported
+	for _, expected := range []string{"/example.ExampleService/MetadataMatch: rule 0:", "/example.ExampleService/RequestMatch: rule 1:"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+	if strings.Count(err.Error(), "rule ") != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// expressions are type checked
+	for expression, expected := range map[string]string{
+		"nosuchfn(user)": "undeclared reference to 'nosuchfn'",
+		"1 + 'a'":        "found no matching overload for '_+_'",
+	} {
+		_, err := cel.NewCelAuthorizer(map[string]*authorize.RuleSet{
+			"/example.ExampleService/RequestMatch": {Rules: []*authorize.Rule{{Expression: expression}}},
+		})
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error %q for %s, got %v", expected, expression, err)
+		}
+	}
+	authz, err := cel.NewCelAuthorizer(rules, cel.WithLazyCompilation())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := authz.Validate(); err == nil {
+		t.Fatalf("expected compile error")
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/MetadataMatch", &authorizer.RuleExecutionParams{}); err == nil {
+		t.Fatalf("expected compile error at request time")
+	}
+	decision, err := authz.Decide(context.Background(), "/example.ExampleService/AllowAll", &authorizer.RuleExecutionParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow: %+v", decision)
+	}
+}
+
+func TestCelAuthorizer_Library(t *testing.T) {
+	tests := []struct {
+		expression  string
+		expectAllow bool
+		expectError bool
+	}{
+		{expression: "hasRole(user, 'admin')", expectAllow: true},
+		{expression: "hasRole(user, 'owner')", expectAllow: false},
+		{expression: "hasRole(['admin'], 'admin')", expectAllow: true},
+		{expression: "ipInCidr(peer.ip, '10.0.0.0/8')", expectAllow: true},
+		{expression: "ipInCidr(peer.ip, '192.168.0.0/16')", expectAllow: false},
+		{expression: "ipInCidr('::ffff:10.1.2.3', '10.0.0.0/8')", expectAllow: true},
+		{expression: "ipInCidr(peer.ip, 'invalid')", expectError: true},
+		{expression: "ipInCidr('', '10.0.0.0/8')", expectAllow: false},
+		{expression: "ipInCidr('not-an-ip', '10.0.0.0/8')", expectAllow: false},
+		{expression: "ipInCidr('', 'invalid')", expectError: true},
+		{expression: "glob(method, '/example.*')", expectAllow: true},
+		{expression: "glob(method, '/admin.*')", expectAllow: false},
+		{expression: "glob(method, '/example.ExampleService/Request?atch')", expectAllow: true},
+		{expression: "regexMatch(method, '^/example[.].+/RequestMatch$')", expectAllow: true},
+		{expression: "regexMatch(method, '(')", expectError: true},
+		{expression: "inTimeWindow(timestamp('2023-01-02T10:00:00Z'), '09:00', '17:00')", expectAllow: true},
+		{expression: "inTimeWindow(timestamp('2023-01-02T17:00:00Z'), '09:00', '17:00')", expectAllow: false},
+		{expression: "inTimeWindow(timestamp('2023-01-02T23:30:00Z'), '22:00', '06:00')", expectAllow: true},
+		{expression: "inTimeWindow(timestamp('2023-01-02T10:00:00Z'), '09:00', '17:00', 'America/Los_Angeles')", expectAllow: false},
+		{expression: "inTimeWindow(now, '9am', '5pm')", expectError: true},
+		{expression: "semverCompare('1.10.0', 'v1.9.2') == 1 && semverCompare('1.0.0-rc.1', '1.0.0') == -1", expectAllow: true},
+		{expression: "semverCompare('latest', '1.0.0') == 0", expectError: true},
+	}
+	for _, opts := range [][]cel.Opt{nil, {cel.WithProtoMessages(), cel.WithUserMessage(&example.User{})}} {
+		for _, tt := range tests {
+			authz, err := cel.NewCelAuthorizer(map[string]*authorize.RuleSet{
+				"/example.ExampleService/RequestMatch": {
+					Rules: []*authorize.Rule{{Expression: tt.expression}},
+				},
+			}, opts...)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.expression, err)
+			}
+			allow, err := authz.AuthorizeMethod(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+				User:    &example.User{Roles: []string{"admin"}},
+				Request: &example.Request{},
+				Peer:    authorizer.NewPeer("10.1.2.3:52342"),
+			})
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("%s: expected error", tt.expression)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.expression, err)
+			}
+			if allow != tt.expectAllow {
+				t.Fatalf("%s: expected allow=%v", tt.expression, tt.expectAllow)
+			}
+		}
+	}
+	// requests without an ip peer (ex: unix sockets or bufconn) are denied instead of failing
+	authz, err := cel.NewCelAuthorizer(map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "ipInCidr(peer.ip, '10.0.0.0/8')"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, peer := range []*authorizer.Peer{nil, authorizer.NewPeer("/tmp/grpc.sock")} {
+		allow, err := authz.AuthorizeMethod(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+			Request: &example.Request{},
+			Peer:    peer,
+		})
+		if err != nil || allow {
+			t.Fatalf("expected deny for peer %v, got %v %v", peer, allow, err)
+		}
+	}
+}
+
+func TestCelAuthorizer_WithFunction(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "isInternal(metadata['x-team'].lowerAscii())"}},
+		},
+	}
+	isInternal := cel.WithFunction("isInternal",
+		googlecel.Overload("is_internal_string", []*googlecel.Type{googlecel.StringType}, googlecel.BoolType,
+			googlecel.UnaryBinding(func(team ref.Val) ref.Val {
+				return types.Bool(team == types.String("platform"))
+			}),
+		),
+	)
+	authz, err := cel.NewCelAuthorizer(rules, isInternal, cel.WithEnvOptions(ext.Strings()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for team, expectAllow := range map[string]bool{"Platform": true, "sales": false} {
+		allow, err := authz.AuthorizeMethod(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+			Metadata: map[string][]string{"x-team": {team}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if allow != expectAllow {
+			t.Fatalf("expected allow=%v for team %s", expectAllow, team)
+		}
+	}
+}
+
+func TestCelAuthorizer_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	authz, err := cel.NewCelAuthorizer(map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "request.AccountId == 'other'"}, {Expression: "true"}},
+		},
+	}, cel.WithTracerProvider(provider))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		Request: &example.Request{AccountId: "123"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected a decision span and 2 rule spans, got %d", len(spans))
+	}
+	// child spans end before their parent
+	decide := spans[2]
+	if decide.Name != cel.Engine+".Decide" {
+		t.Fatalf("expected decision span, got %s", decide.Name)
+	}
+	attributes := attribute.NewSet(decide.Attributes...)
+	for key, expected := range map[attribute.Key]any{
+		authorizer.AttributeMethod:    "/example.ExampleService/RequestMatch",
+		authorizer.AttributeDecision:  string(authorizer.EffectAllow),
+		authorizer.AttributeEngine:    cel.Engine,
+		authorizer.AttributeRuleIndex: int64(1),
+	} {
+		if v, ok := attributes.Value(key); !ok || v.AsInterface() != expected {
+			t.Fatalf("expected %s to be %v, got %v", key, expected, v.AsInterface())
+		}
+	}
+	for i, span := range spans[:2] {
+		if span.Name != cel.Engine+".EvaluateRule" || span.Parent.SpanID() != decide.SpanContext.SpanID() {
+			t.Fatalf("expected rule span to be a child of the decision span, got %s", span.Name)
+		}
+		attributes := attribute.NewSet(span.Attributes...)
+		if v, _ := attributes.Value(authorizer.AttributeRuleIndex); v.AsInt64() != int64(i) {
+			t.Fatalf("expected rule index %d, got %v", i, v.AsInt64())
+		}
+		if v, _ := attributes.Value(authorizer.AttributeRuleResult); v.AsBool() != (i == 1) {
+			t.Fatalf("expected rule %d result %v, got %v", i, i == 1, v.AsBool())
+		}
+	}
+}
+
+func TestCelAuthorizer_Limits(t *testing.T) {
+	items := make([]any, 10000)
+	for i := range items {
+		items[i] = i
+	}
+	params := &authorizer.RuleExecutionParams{
+		Request: &example.Request{},
+		User:    map[string]any{"items": items},
+	}
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "user.items.all(x, x >= 0)"}},
+		},
+		"/example.ExampleService/MetadataMatch": {
+			Rules: []*authorize.Rule{{Expression: "user.items.all(x, x >= 0)", CostLimit: 1000000}},
+		},
+	}
+	authz, err := cel.NewCelAuthorizer(rules, cel.WithCostLimit(100))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", params); err == nil || !strings.Contains(err.Error(), "cost limit exceeded") {
+		t.Fatalf("expected cost limit error, got %v", err)
+	}
+	// the rule's cost limit overrides the authorizer's cost limit
+	if decision, err := authz.Decide(context.Background(), "/example.ExampleService/MetadataMatch", params); err != nil || !decision.Allowed() {
+		t.Fatalf("expected allow, got %+v: %v", decision, err)
+	}
+	// expressions are interrupted when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := authz.Decide(ctx, "/example.ExampleService/MetadataMatch", params); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected interrupted evaluation, got %v", err)
+	}
+	authz, err = cel.NewCelAuthorizer(rules, cel.WithMaxDuration(time.Nanosecond), cel.WithInterruptCheckFrequency(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/MetadataMatch", params); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected interrupted evaluation, got %v", err)
+	}
+}
+
 /*
 BenchmarkCelAuthorizer_AuthorizeMethod
 BenchmarkCelAuthorizer_AuthorizeMethod/basic_request_field_rule_1_(allow)
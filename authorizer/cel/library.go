@@ -0,0 +1,272 @@
+package cel
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"golang.org/x/mod/semver"
+)
+
+// Library returns the built-in authorization function library. It is included in the env of every CelAuthorizer and
+// of the protoc-gen-authorize type checker:
+//
+//	hasRole(user, 'admin')                               // the user's roles (or Roles) field contains the role
+//	ipInCidr(peer.ip, '10.0.0.0/8')                      // the ip address is in the CIDR block (false if it isn't an ip address)
+//	glob(method, '/admin.*')                             // the string matches the pattern (* is any sequence, ? is any character)
+//	regexMatch(request.Email, '^.+@example[.]com$')      // the string matches the RE2 pattern (compiled once)
+//	inTimeWindow(now, '09:00', '17:00')                  // the time of day is in the window (in UTC)
+//	inTimeWindow(now, '22:00', '06:00', 'Europe/Paris')  // the window may wrap midnight and have a time zone
+//	semverCompare(request.Version, '1.2.0') >= 0         // -1, 0 or 1 as the first version is lower, equal or higher
+func Library() cel.EnvOption {
+	return cel.Lib(library{})
+}
+
+type library struct{}
+
+func (library) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("hasRole",
+			cel.Overload("has_role_dyn_string", []*cel.Type{cel.DynType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(hasRole),
+			),
+		),
+		cel.Function("ipInCidr",
+			cel.Overload("ip_in_cidr_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(stringsBinding(ipInCidr)),
+			),
+		),
+		cel.Function("glob",
+			cel.Overload("glob_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(stringsBinding(glob)),
+			),
+		),
+		cel.Function("regexMatch",
+			cel.Overload("regex_match_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(stringsBinding(regexMatch)),
+			),
+		),
+		cel.Function("inTimeWindow",
+			cel.Overload("in_time_window_timestamp_string_string", []*cel.Type{cel.TimestampType, cel.StringType, cel.StringType}, cel.BoolType,
+				cel.FunctionBinding(inTimeWindow),
+			),
+			cel.Overload("in_time_window_timestamp_string_string_string", []*cel.Type{cel.TimestampType, cel.StringType, cel.StringType, cel.StringType}, cel.BoolType,
+				cel.FunctionBinding(inTimeWindow),
+			),
+		),
+		cel.Function("semverCompare",
+			cel.Overload("semver_compare_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.IntType,
+				cel.BinaryBinding(semverCompare),
+			),
+		),
+	}
+}
+
+func (library) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// roleFields are the fields of the user that hasRole looks up, in order
+var roleFields = []string{"roles", "Roles"}
+
+func hasRole(user, role ref.Val) ref.Val {
+	var roles ref.Val
+	for _, field := range roleFields {
+		switch u := user.(type) {
+		case traits.Mapper:
+			if v, found := u.Find(types.String(field)); found {
+				roles = v
+			}
+		case traits.FieldTester:
+			// proto messages
+			if u.IsSet(types.String(field)) == types.True {
+				roles = u.(traits.Indexer).Get(types.String(field))
+			}
+		case traits.Lister:
+			roles = u
+		}
+		if roles != nil {
+			break
+		}
+	}
+	switch r := roles.(type) {
+	case nil:
+		return types.False
+	case traits.Lister:
+		for it := r.Iterator(); it.HasNext() == types.True; {
+			if it.Next().Equal(role) == types.True {
+				return types.True
+			}
+		}
+		return types.False
+	default:
+		return r.Equal(role)
+	}
+}
+
+// stringsBinding adapts a function of two strings to a cel binary binding
+func stringsBinding(fn func(a, b string) (bool, error)) func(lhs, rhs ref.Val) ref.Val {
+	return func(lhs, rhs ref.Val) ref.Val {
+		a, ok := lhs.(types.String)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(lhs)
+		}
+		b, ok := rhs.(types.String)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(rhs)
+		}
+		result, err := fn(string(a), string(b))
+		if err != nil {
+			return types.NewErr("%v", err)
+		}
+		return types.Bool(result)
+	}
+}
+
+func ipInCidr(ip, cidr string) (bool, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false, fmt.Errorf("ipInCidr: %v", err)
+	}
+	// peers without an ip address (ex: unix sockets) are not in any CIDR block
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, nil
+	}
+	return prefix.Contains(addr.Unmap()), nil
+}
+
+func glob(s, pattern string) (bool, error) {
+	re, err := patterns.compile("glob:"+pattern, func() (*regexp.Regexp, error) {
+		var b strings.Builder
+		b.WriteString("^")
+		for _, r := range pattern {
+			switch r {
+			case '*':
+				b.WriteString(".*")
+			case '?':
+				b.WriteString(".")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		b.WriteString("$")
+		return regexp.Compile(b.String())
+	})
+	if err != nil {
+		return false, fmt.Errorf("glob: %v", err)
+	}
+	return re.MatchString(s), nil
+}
+
+func regexMatch(s, pattern string) (bool, error) {
+	re, err := patterns.compile("regex:"+pattern, func() (*regexp.Regexp, error) {
+		return regexp.Compile(pattern)
+	})
+	if err != nil {
+		return false, fmt.Errorf("regexMatch: %v", err)
+	}
+	return re.MatchString(s), nil
+}
+
+// maxCachedPatterns bounds the pattern cache, since patterns may be built from request data
+const maxCachedPatterns = 1000
+
+// patterns caches the compiled regular expressions of glob and regexMatch
+var patterns = &patternCache{patterns: map[string]*regexp.Regexp{}}
+
+type patternCache struct {
+	mu       sync.RWMutex
+	patterns map[string]*regexp.Regexp
+}
+
+func (p *patternCache) compile(key string, compile func() (*regexp.Regexp, error)) (*regexp.Regexp, error) {
+	p.mu.RLock()
+	re, ok := p.patterns[key]
+	p.mu.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := compile()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	if len(p.patterns) < maxCachedPatterns {
+		p.patterns[key] = re
+	}
+	p.mu.Unlock()
+	return re, nil
+}
+
+// locations caches the time zones of inTimeWindow
+var locations sync.Map
+
+func inTimeWindow(args ...ref.Val) ref.Val {
+	ts, ok := args[0].(types.Timestamp)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(args[0])
+	}
+	var bounds [2]time.Duration
+	for i, arg := range args[1:3] {
+		s, ok := arg.(types.String)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(arg)
+		}
+		t, err := time.Parse("15:04", string(s))
+		if err != nil {
+			return types.NewErr("inTimeWindow: invalid time of day %q, expected HH:MM", string(s))
+		}
+		bounds[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	t := ts.Time.UTC()
+	if len(args) == 4 {
+		name, ok := args[3].(types.String)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(args[3])
+		}
+		loc, ok := locations.Load(string(name))
+		if !ok {
+			l, err := time.LoadLocation(string(name))
+			if err != nil {
+				return types.NewErr("inTimeWindow: %v", err)
+			}
+			loc, _ = locations.LoadOrStore(string(name), l)
+		}
+		t = t.In(loc.(*time.Location))
+	}
+	timeOfDay := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	start, end := bounds[0], bounds[1]
+	// the start of the window is inclusive and the end is exclusive. Windows that end before they start wrap midnight
+	if start <= end {
+		return types.Bool(timeOfDay >= start && timeOfDay < end)
+	}
+	return types.Bool(timeOfDay >= start || timeOfDay < end)
+}
+
+func semverCompare(lhs, rhs ref.Val) ref.Val {
+	var versions [2]string
+	for i, arg := range []ref.Val{lhs, rhs} {
+		s, ok := arg.(types.String)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(arg)
+		}
+		// versions may omit the v prefix
+		v := string(s)
+		if !strings.HasPrefix(v, "v") {
+			v = "v" + v
+		}
+		if !semver.IsValid(v) {
+			return types.NewErr("semverCompare: invalid version %q", string(s))
+		}
+		versions[i] = v
+	}
+	return types.Int(semver.Compare(versions[0], versions[1]))
+}
@@ -0,0 +1,174 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ContextWithUser returns a copy of the context with the user stored under the DefaultUserExtractorKey so it can be
+// extracted with the DefaultUserExtractor. This is useful for client interceptors where the user is known by the caller
+func ContextWithUser(ctx context.Context, user any) context.Context {
+	return context.WithValue(ctx, DefaultUserExtractorKey, user)
+}
+
+// UnaryClientInterceptor uses the given authorizer to authorize outgoing unary grpc requests before they are sent to the server.
+// The rules are evaluated against the outgoing request and outgoing metadata, and the user extractor is called with the client context
+// (see ContextWithUser). The peer variable is the target of the client connection.
+// Authorizers that don't implement DecisionAuthorizer are adapted with AsDecisionAuthorizer
+func UnaryClientInterceptor(authz Authorizer, opts ...Opt) grpc.UnaryClientInterceptor {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	authorizer := AsDecisionAuthorizer(authz)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if len(o.selectors) > 0 {
+			meta := interceptors.NewClientCallMeta(method, nil, req)
+			for _, s := range o.selectors {
+				if s.Match(ctx, meta) {
+					return unaryClientInterceptor(authorizer, o)(ctx, method, req, reply, cc, invoker, callOpts...)
+				}
+			}
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		return unaryClientInterceptor(authorizer, o)(ctx, method, req, reply, cc, invoker, callOpts...)
+	}
+}
+
+func unaryClientInterceptor(authorizer DecisionAuthorizer, o *options) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		for _, m := range o.whiteListMethods {
+			if m == method {
+				return invoker(ctx, method, req, reply, cc, callOpts...)
+			}
+		}
+		md, _ := metadata.FromOutgoingContext(ctx)
+		usr, err := o.extractUser(ctx, method, md, false)
+		if err != nil {
+			return err
+		}
+		if err := o.authorize(ctx, authorizer, method, &RuleExecutionParams{
+			User:     usr,
+			Request:  req,
+			Metadata: md,
+			Peer:     NewPeer(cc.Target()),
+		}); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
+
+// StreamClientInterceptor uses the given authorizer to authorize outgoing streaming grpc requests before they are sent to the server.
+// The request object in the expression evaluation is nil because the stream is authorized when it is opened.
+// If the method's RuleSet enables authorize_stream_messages (see RuleSetLookup), the rules are also evaluated against
+// every message sent by the client and sending fails on the first denied message.
+// Authorizers that don't implement DecisionAuthorizer are adapted with AsDecisionAuthorizer
+func StreamClientInterceptor(authz Authorizer, opts ...Opt) grpc.StreamClientInterceptor {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	authorizer := AsDecisionAuthorizer(authz)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if len(o.selectors) > 0 {
+			meta := interceptors.NewClientCallMeta(method, desc, nil)
+			for _, s := range o.selectors {
+				if s.Match(ctx, meta) {
+					return streamClientInterceptor(authorizer, o)(ctx, desc, cc, method, streamer, callOpts...)
+				}
+			}
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+		return streamClientInterceptor(authorizer, o)(ctx, desc, cc, method, streamer, callOpts...)
+	}
+}
+
+func streamClientInterceptor(authorizer DecisionAuthorizer, o *options) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		for _, m := range o.whiteListMethods {
+			if m == method {
+				return streamer(ctx, desc, cc, method, callOpts...)
+			}
+		}
+		md, _ := metadata.FromOutgoingContext(ctx)
+		usr, err := o.extractUser(ctx, method, md, true)
+		if err != nil {
+			return nil, err
+		}
+		// the stream is authorized when it is opened, even if its messages are authorized individually
+		if err := o.authorize(ctx, authorizer, method, &RuleExecutionParams{
+			User:     usr,
+			Metadata: md,
+			IsStream: true,
+			Peer:     NewPeer(cc.Target()),
+		}); err != nil {
+			return nil, err
+		}
+		if desc.ClientStreams && lookupRuleSet(authorizer, method).GetAuthorizeStreamMessages() {
+			// the stream is canceled when a message is denied so it isn't left open
+			ctx, cancel := context.WithCancel(ctx)
+			cs, err := streamer(ctx, desc, cc, method, callOpts...)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			return &authorizedClientStream{
+				ClientStream:  cs,
+				cancel:        cancel,
+				serverStreams: desc.ServerStreams,
+				authorizer:    authorizer,
+				opts:          o,
+				method:        method,
+				user:          usr,
+				md:            md,
+				peer:          NewPeer(cc.Target()),
+			}, nil
+		}
+		return streamer(ctx, desc, cc, method, callOpts...)
+	}
+}
+
+// authorizedClientStream authorizes every message sent by the client
+type authorizedClientStream struct {
+	grpc.ClientStream
+	cancel        context.CancelFunc
+	serverStreams bool
+	authorizer    DecisionAuthorizer
+	opts          *options
+	method        string
+	user          any
+	md            metadata.MD
+	peer          *Peer
+	index         int
+}
+
+// SendMsg evaluates the method's rules against the message before sending it to the server
+func (s *authorizedClientStream) SendMsg(m any) error {
+	params := &RuleExecutionParams{
+		User:         s.user,
+		Request:      m,
+		Metadata:     s.md,
+		IsStream:     true,
+		MessageIndex: s.index,
+		Peer:         s.peer,
+	}
+	s.index++
+	if err := s.opts.authorize(s.Context(), s.authorizer, s.method, params); err != nil {
+		s.cancel()
+		return err
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+// RecvMsg receives a message from the server and releases the context of the stream once the stream is finished
+func (s *authorizedClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.cancel()
+	}
+	return err
+}
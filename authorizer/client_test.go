@@ -0,0 +1,104 @@
+package authorizer
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// fakeClientStream records the messages sent to the server
+type fakeClientStream struct {
+	grpc.ClientStream
+	ctx  context.Context
+	sent []any
+}
+
+func (f *fakeClientStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeClientStream) SendMsg(m any) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func (f *fakeClientStream) RecvMsg(m any) error {
+	return nil
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	const method = "/example.ExampleService/StreamMatch"
+	authz := &streamAuthorizer{rules: map[string]*authorize.RuleSet{
+		method: {Rules: []*authorize.Rule{{Expression: "true"}}, AuthorizeStreamMessages: true},
+	}}
+	// the stream isn't sent to the network, so nothing has to listen on the target
+	cc, err := grpc.Dial(":0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer cc.Close()
+	interceptor := StreamClientInterceptor(authz, WithUserExtractor(func(ctx context.Context) (any, error) {
+		return "admin", nil
+	}))
+	desc := &grpc.StreamDesc{ClientStreams: true}
+	open := func(t *testing.T) (grpc.ClientStream, *fakeClientStream) {
+		t.Helper()
+		var fake *fakeClientStream
+		cs, err := interceptor(context.Background(), desc, cc, method, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			fake = &fakeClientStream{ctx: ctx}
+			return fake, nil
+		})
+		if err != nil {
+			t.Fatalf("failed to open stream: %v", err)
+		}
+		return cs, fake
+	}
+	t.Run("denied message cancels the stream", func(t *testing.T) {
+		cs, fake := open(t)
+		if err := cs.SendMsg(&authorize.Rule{Expression: "true"}); err != nil {
+			t.Fatalf("failed to send message: %v", err)
+		}
+		if err := cs.SendMsg(&authorize.Rule{Expression: "false"}); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected code %v, got %v", codes.PermissionDenied, err)
+		}
+		if len(fake.sent) != 1 {
+			t.Fatalf("expected 1 sent message, got %d", len(fake.sent))
+		}
+		if fake.ctx.Err() != context.Canceled {
+			t.Fatalf("expected the stream to be canceled, got %v", fake.ctx.Err())
+		}
+	})
+	t.Run("finished stream releases its context", func(t *testing.T) {
+		cs, fake := open(t)
+		if err := cs.SendMsg(&authorize.Rule{Expression: "true"}); err != nil {
+			t.Fatalf("failed to send message: %v", err)
+		}
+		if fake.ctx.Err() != nil {
+			t.Fatalf("expected the stream to be open, got %v", fake.ctx.Err())
+		}
+		if err := cs.RecvMsg(&authorize.Rule{}); err != nil {
+			t.Fatalf("failed to receive message: %v", err)
+		}
+		if fake.ctx.Err() != context.Canceled {
+			t.Fatalf("expected the context of the stream to be released, got %v", fake.ctx.Err())
+		}
+	})
+	t.Run("denied stream is not opened", func(t *testing.T) {
+		interceptor := StreamClientInterceptor(authz, WithUserExtractor(func(ctx context.Context) (any, error) {
+			return "guest", nil
+		}))
+		_, err := interceptor(context.Background(), desc, cc, method, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			t.Fatal("the stream was opened")
+			return nil, nil
+		})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected code %v, got %v", codes.PermissionDenied, err)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package authorizer
+
+import (
+	"context"
+	"time"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// Effect is the outcome of an authorization decision
+type Effect string
+
+const (
+	// EffectAllow means the request is authorized
+	EffectAllow Effect = "allow"
+	// EffectDeny means the request is not authorized
+	EffectDeny Effect = "deny"
+)
+
+// Decision is the structured result of authorizing a grpc request
+type Decision struct {
+	// Effect is the outcome of the decision
+	Effect Effect
+	// RuleIndex is the index of the rule that determined the outcome, or -1 if no rule matched
+	RuleIndex int
+	// RuleName is the name of the rule that determined the outcome, if it has one
+	RuleName string
+	// Expression is the expression of the rule that determined the outcome
+	Expression string
+	// RulesEvaluated is the number of rule expressions that were evaluated to reach the decision
+	RulesEvaluated int
+	// Engine is the name of the expression engine that made the decision (ex: cel, javascript)
+	Engine string
+	// EvaluationTime is the time it took to reach the decision
+	EvaluationTime time.Duration
+	// Reason is a human readable explanation of why the request was denied
+	Reason string
+	// Denial is the custom error of the rule or RuleSet that denied the request, if it has one (see DenialError)
+	Denial *authorize.Denial
+	// Cached is true if the decision was served from a CachingAuthorizer
+	Cached bool
+}
+
+// Allowed returns true if the decision authorizes the request
+func (d *Decision) Allowed() bool {
+	return d != nil && d.Effect == EffectAllow
+}
+
+// DecisionAuthorizer is an Authorizer that also returns a structured Decision describing
+// which rule authorized or denied the request
+type DecisionAuthorizer interface {
+	Authorizer
+	// Decide is called by the grpc interceptor to authorize a request
+	Decide(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error)
+}
+
+// DecideFunc is a function that authorizes a grpc request and returns a structured Decision
+type DecideFunc func(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error)
+
+// Decide implements the DecisionAuthorizer interface
+func (f DecideFunc) Decide(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+	return f(ctx, method, params)
+}
+
+// AuthorizeMethod implements the Authorizer interface
+func (f DecideFunc) AuthorizeMethod(ctx context.Context, method string, params *RuleExecutionParams) (bool, error) {
+	decision, err := f(ctx, method, params)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+// AsDecisionAuthorizer adapts an Authorizer to a DecisionAuthorizer. Authorizers that already implement
+// DecisionAuthorizer are returned as is. Otherwise, the returned decision only reports the effect and evaluation time.
+func AsDecisionAuthorizer(authorizer Authorizer) DecisionAuthorizer {
+	if d, ok := authorizer.(DecisionAuthorizer); ok {
+		return d
+	}
+	return DecideFunc(func(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+		start := time.Now()
+		allow, err := authorizer.AuthorizeMethod(ctx, method, params)
+		if err != nil {
+			return nil, err
+		}
+		decision := &Decision{
+			Effect:         EffectDeny,
+			RuleIndex:      -1,
+			EvaluationTime: time.Since(start),
+		}
+		if allow {
+			decision.Effect = EffectAllow
+		} else {
+			decision.Reason = "authorizer denied the request"
+		}
+		return decision, nil
+	})
+}
@@ -0,0 +1,109 @@
+package authorizer
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultShadowTimeout is the default maximum duration of the evaluation of a candidate authorizer (see WithShadowTimeout)
+const DefaultShadowTimeout = 100 * time.Millisecond
+
+// DryRunEvent is the would-be decision of a request that was authorized in dry run mode
+type DryRunEvent struct {
+	// Method is the grpc method
+	Method string
+	// Params are the parameters the rules were evaluated with
+	Params *RuleExecutionParams
+	// Decision is the decision that would have been enforced. It is nil if Err is set
+	Decision *Decision
+	// Err is the error returned by the authorizer
+	Err error
+}
+
+// DryRunHandler is called with the would-be decision of every request authorized in dry run mode
+type DryRunHandler func(ctx context.Context, event *DryRunEvent)
+
+// ShadowEvent reports a disagreement between the enforced authorizer and a candidate authorizer evaluated in shadow
+type ShadowEvent struct {
+	// Method is the grpc method
+	Method string
+	// Params are the parameters the rules were evaluated with
+	Params *RuleExecutionParams
+	// Enforced is the decision of the enforced authorizer. It is nil if the enforced authorizer returned an error
+	Enforced *Decision
+	// Candidate is the decision of the candidate authorizer. It is nil if Err is set
+	Candidate *Decision
+	// Err is the error returned by the candidate authorizer
+	Err error
+}
+
+// ShadowHandler is called when a candidate authorizer evaluated in shadow disagrees with the enforced authorizer
+type ShadowHandler func(ctx context.Context, event *ShadowEvent)
+
+// WithDryRun evaluates the rules of every method without enforcing them: the would-be decision is passed to the handler
+// and the request is allowed, even if the rules deny it or fail to evaluate. Dry run mode only applies to the rules -
+// user extraction errors (ex: a missing or invalid token) are still returned.
+// Methods can also be put in dry run mode individually with the dry_run RuleSet option
+func WithDryRun(handler DryRunHandler) Opt {
+	return func(o *options) {
+		o.dryRun = true
+		o.dryRunHandler = handler
+	}
+}
+
+// WithDryRunHandler sets the handler that receives the would-be decision of methods whose RuleSet enables dry_run,
+// without putting the other methods in dry run mode
+func WithDryRunHandler(handler DryRunHandler) Opt {
+	return func(o *options) {
+		o.dryRunHandler = handler
+	}
+}
+
+// WithShadowAuthorizer evaluates the candidate authorizer next to the enforced authorizer and passes their disagreements
+// (and the candidate's errors) to the handler. The candidate's decisions are never enforced, so it can be used to
+// compare a new rule set against the current one in production.
+// The candidate is evaluated synchronously after the enforced authorizer, so it adds its evaluation time to the latency
+// of every request. Its evaluation is bounded by the shadow timeout (see WithShadowTimeout)
+func WithShadowAuthorizer(candidate Authorizer, handler ShadowHandler) Opt {
+	return func(o *options) {
+		o.shadowAuthorizer = AsDecisionAuthorizer(candidate)
+		o.shadowHandler = handler
+	}
+}
+
+// WithShadowTimeout sets the maximum duration of the evaluation of the candidate authorizer of WithShadowAuthorizer.
+// Candidates that take longer are interrupted and the timeout error is passed to the shadow handler. Defaults to DefaultShadowTimeout
+func WithShadowTimeout(timeout time.Duration) Opt {
+	return func(o *options) {
+		o.shadowTimeout = timeout
+	}
+}
+
+// isDryRun returns true if the method's decision shouldn't be enforced
+func (o *options) isDryRun(authorizer DecisionAuthorizer, method string) bool {
+	return o.dryRun || lookupRuleSet(authorizer, method).GetDryRun()
+}
+
+// shadow evaluates the candidate authorizer and reports disagreements with the enforced decision
+func (o *options) shadow(ctx context.Context, method string, params *RuleExecutionParams, enforced *Decision) {
+	if o.shadowAuthorizer == nil || o.shadowHandler == nil {
+		return
+	}
+	timeout := o.shadowTimeout
+	if timeout <= 0 {
+		timeout = DefaultShadowTimeout
+	}
+	shadowCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	candidate, err := o.shadowAuthorizer.Decide(shadowCtx, method, params)
+	if err == nil && enforced != nil && candidate.Allowed() == enforced.Allowed() {
+		return
+	}
+	o.shadowHandler(ctx, &ShadowEvent{
+		Method:    method,
+		Params:    params,
+		Enforced:  enforced,
+		Candidate: candidate,
+		Err:       err,
+	})
+}
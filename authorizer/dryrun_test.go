@@ -0,0 +1,89 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDryRun(t *testing.T) {
+	const method = "/example.ExampleService/RequestMatch"
+	deny := DecideFunc(func(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+		return &Decision{Effect: EffectDeny, RuleIndex: -1}, nil
+	})
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+	type test struct {
+		name         string
+		extractor    UserExtractor
+		expectCode   codes.Code
+		expectEvents int
+	}
+	tests := []test{
+		{
+			name: "denied request is allowed",
+			extractor: func(ctx context.Context) (any, error) {
+				return "user", nil
+			},
+			expectCode:   codes.OK,
+			expectEvents: 1,
+		},
+		{
+			name: "user extraction errors are returned",
+			extractor: func(ctx context.Context) (any, error) {
+				return nil, errors.New("missing token")
+			},
+			expectCode: codes.Unauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*DryRunEvent
+			interceptor := UnaryServerInterceptor(deny, WithUserExtractor(tt.extractor), WithDryRun(func(ctx context.Context, event *DryRunEvent) {
+				events = append(events, event)
+			}))
+			_, err := interceptor(context.Background(), nil, info, handler)
+			if status.Code(err) != tt.expectCode {
+				t.Fatalf("expected code %v, got %v", tt.expectCode, err)
+			}
+			if len(events) != tt.expectEvents {
+				t.Fatalf("expected %d dry run events, got %d", tt.expectEvents, len(events))
+			}
+		})
+	}
+}
+
+func TestShadowTimeout(t *testing.T) {
+	const method = "/example.ExampleService/RequestMatch"
+	allow := DecideFunc(func(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+		return &Decision{Effect: EffectAllow, RuleIndex: 0}, nil
+	})
+	// the candidate never decides before its context is done
+	slow := DecideFunc(func(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	var events []*ShadowEvent
+	interceptor := UnaryServerInterceptor(allow, WithShadowAuthorizer(slow, func(ctx context.Context, event *ShadowEvent) {
+		events = append(events, event)
+	}), WithShadowTimeout(10*time.Millisecond))
+	start := time.Now()
+	if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the candidate to be interrupted after the shadow timeout, took %v", elapsed)
+	}
+	if len(events) != 1 || !errors.Is(events[0].Err, context.DeadlineExceeded) || !events[0].Enforced.Allowed() {
+		t.Fatalf("expected 1 shadow event with a timeout error, got %+v", events)
+	}
+}
@@ -0,0 +1,319 @@
+package expr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	exprlang "github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/types"
+	"github.com/expr-lang/expr/vm"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+
+	"github.com/autom8ter/protoc-gen-authorize/authorizer"
+)
+
+// Engine is the name of the expr expression engine reported in authorization decisions
+const Engine = "expr"
+
+// Opt is a functional option for configuring an ExprAuthorizer
+type Opt func(*ExprAuthorizer)
+
+// WithVariables sets additional variables/functions that will be available to expressions
+func WithVariables(variables map[string]any) Opt {
+	return func(e *ExprAuthorizer) {
+		for k, v := range variables {
+			e.variables[k] = v
+		}
+	}
+}
+
+// WithOptions sets additional expr options (ex: expr.Function or expr.Operator) that are used to compile every expression
+func WithOptions(opts ...exprlang.Option) Opt {
+	return func(e *ExprAuthorizer) {
+		e.options = append(e.options, opts...)
+	}
+}
+
+// WithMissingRulesPolicy sets how requests to methods that have no rules are authorized.
+// Defaults to authorizer.MissingRulesAllowIfServiceAnnotated
+func WithMissingRulesPolicy(policy authorizer.MissingRulesPolicy) Opt {
+	return func(e *ExprAuthorizer) {
+		e.missingRules = policy
+	}
+}
+
+// WithTracerProvider sets the tracer provider used to record a span for every decision, with a child span for every
+// rule expression that is evaluated
+func WithTracerProvider(provider trace.TracerProvider) Opt {
+	return func(e *ExprAuthorizer) {
+		e.tracer = provider.Tracer(authorizer.TracerName)
+	}
+}
+
+// WithMaxDuration sets the maximum time the rule expressions of a request may run. The expr vm can't be interrupted, so
+// the duration is only checked between rules: the remaining rules are skipped once the duration elapses or the context
+// of the request is done. Use WithMemoryBudget to bound the evaluation of a single expression. Defaults to no limit
+func WithMaxDuration(maxDuration time.Duration) Opt {
+	return func(e *ExprAuthorizer) {
+		e.maxDuration = maxDuration
+	}
+}
+
+// WithMemoryBudget sets the maximum memory, in expr vm units (roughly the number of elements allocated by ranges, arrays,
+// maps and builtins like map or filter), a single expression may allocate. Expressions that exceed it fail with an error.
+// The number of nodes of an expression is limited with WithOptions(expr.MaxNodes(n)). Defaults to conf.DefaultMemoryBudget
+func WithMemoryBudget(budget uint) Opt {
+	return func(e *ExprAuthorizer) {
+		e.memoryBudget = budget
+	}
+}
+
+// WithLazyCompilation defers the compilation of rule expressions to the first request of their method instead of
+// compiling every expression in NewExprAuthorizer. Invalid expressions are then only reported by Validate or at request time
+func WithLazyCompilation() Opt {
+	return func(e *ExprAuthorizer) {
+		e.lazy = true
+	}
+}
+
+// ExprAuthorizer is an expr (https://expr-lang.org) vm that uses expr expressions to authorize grpc requests.
+// The request and user are evaluated as is, so expressions reference the fields of go structs by their go field names (ex: request.AccountId)
+type ExprAuthorizer struct {
+	rules          map[string]*authorize.RuleSet
+	cachedPrograms sync.Map
+	variables      map[string]any
+	options        []exprlang.Option
+	missingRules   authorizer.MissingRulesPolicy
+	tracer         trace.Tracer
+	maxDuration    time.Duration
+	memoryBudget   uint
+	lazy           bool
+}
+
+// NewExprAuthorizer returns a new ExprAuthorizer. The rules map is a map of method names to RuleSets. The RuleSets are used to
+// authorize the method. The rules of a RuleSet are combined using the RuleSet's combining algorithm - by default,
+// the effect of the first rule that evaluates to true is applied to the request. The mapping can be generated with the protoc-gen-authorize plugin.
+// Every rule expression is compiled up front and an error describing each expression that fails to compile is returned,
+// unless WithLazyCompilation is set.
+func NewExprAuthorizer(rules map[string]*authorize.RuleSet, opts ...Opt) (*ExprAuthorizer, error) {
+	e := &ExprAuthorizer{
+		rules:          rules,
+		cachedPrograms: sync.Map{},
+		variables:      map[string]any{},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if !e.lazy {
+		if err := e.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// LoadExprAuthorizer loads a YAML or JSON policy document of method names to RuleSets (see authorizer.ParsePolicy) and returns
+// a new ExprAuthorizer for it. The policy is rejected if any of its expressions fail to compile
+func LoadExprAuthorizer(path string, opts ...Opt) (*ExprAuthorizer, error) {
+	rules, err := authorizer.LoadPolicy(path)
+	if err != nil {
+		return nil, err
+	}
+	e, err := NewExprAuthorizer(rules, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if e.lazy {
+		if err := e.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Factory returns an authorizer.AuthorizerFactory that creates an ExprAuthorizer with the given options, for example
+// to hot reload a policy with authorizer.NewReloadingAuthorizer
+func Factory(opts ...Opt) authorizer.AuthorizerFactory {
+	return func(rules map[string]*authorize.RuleSet) (authorizer.Authorizer, error) {
+		return NewExprAuthorizer(rules, opts...)
+	}
+}
+
+// Variables returns the types of the variables injected into every expression. It is the env expressions are compiled
+// against, along with the variables of WithVariables
+func Variables() types.Map {
+	return types.Map{
+		string(authorizer.ExpressionVarMetadata):       types.TypeOf(map[string]string{}),
+		string(authorizer.ExpressionVarMetadataValues): types.TypeOf(map[string][]any{}),
+		string(authorizer.ExpressionVarRequest):        types.Any,
+		string(authorizer.ExpressionVarUser):           types.Any,
+		string(authorizer.ExpressionVarIsStream):       types.Bool,
+		string(authorizer.ExpressionVarMessageIndex):   types.Int,
+		string(authorizer.ExpressionVarMethod):         types.String,
+		string(authorizer.ExpressionVarPeer):           types.TypeOf(map[string]any{}),
+		// deadline is a time.Time, or nil if the request has no deadline
+		string(authorizer.ExpressionVarDeadline): types.Any,
+		string(authorizer.ExpressionVarNow):      types.TypeOf(time.Time{}),
+	}
+}
+
+// Validate compiles the expressions of every method's rules and returns an error describing each expression that fails to compile
+func (e *ExprAuthorizer) Validate() error {
+	methods := make([]string, 0, len(e.rules))
+	for method := range e.rules {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	var errs []error
+	for _, method := range methods {
+		for i, rule := range e.rules[method].GetRules() {
+			if _, err := e.getProgram(rule); err != nil {
+				errs = append(errs, fmt.Errorf("%s: rule %d: %w", method, i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RuleSet returns the RuleSet of the given method
+func (e *ExprAuthorizer) RuleSet(method string) (*authorize.RuleSet, bool) {
+	rules, ok := e.rules[method]
+	return rules, ok
+}
+
+// AuthorizeMethod authorizes a gRPC method the RuleExecutionParams and returns a boolean representing whether the
+// request is authorized or not.
+func (e *ExprAuthorizer) AuthorizeMethod(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (bool, error) {
+	decision, err := e.Decide(ctx, method, params)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+// Decide authorizes a gRPC method the RuleExecutionParams and returns a Decision describing which rule
+// authorized the request, or why it was denied.
+func (e *ExprAuthorizer) Decide(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (*authorizer.Decision, error) {
+	return authorizer.TraceDecision(ctx, e.tracer, Engine+".Decide", method, params, e.decide)
+}
+
+func (e *ExprAuthorizer) decide(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (*authorizer.Decision, error) {
+	start := time.Now()
+	decision := &authorizer.Decision{
+		Effect:    authorizer.EffectDeny,
+		RuleIndex: -1,
+		Engine:    Engine,
+	}
+	defer func() {
+		decision.EvaluationTime = time.Since(start)
+	}()
+	rules, ok := e.rules[method]
+	if !ok {
+		if err := authorizer.DecideMissingRules(e.missingRules, e.rules, method, decision); err != nil {
+			return nil, err
+		}
+		return decision, nil
+	}
+	if len(rules.Rules) == 1 && rules.Rules[0].Expression == authorizer.WildcardExpression {
+		if err := authorizer.EvaluateRuleSet(rules, decision, nil); err != nil {
+			return nil, err
+		}
+		return decision, nil
+	}
+	programs, err := e.getMethodPrograms(rules)
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]any, len(e.variables)+10)
+	for k, v := range e.variables {
+		env[k] = v
+	}
+	metaMap := map[string]string{}
+	for k, v := range params.Metadata {
+		metaMap[k] = strings.Join(v, ",")
+	}
+	now := params.Now
+	if now.IsZero() {
+		now = start
+	}
+	var deadline any
+	if !params.Deadline.IsZero() {
+		deadline = params.Deadline
+	}
+	env[string(authorizer.ExpressionVarMetadata)] = metaMap
+	env[string(authorizer.ExpressionVarMetadataValues)] = authorizer.MetadataValues(params.Metadata)
+	env[string(authorizer.ExpressionVarRequest)] = params.Request
+	env[string(authorizer.ExpressionVarUser)] = params.User
+	env[string(authorizer.ExpressionVarIsStream)] = params.IsStream
+	env[string(authorizer.ExpressionVarMessageIndex)] = params.MessageIndex
+	env[string(authorizer.ExpressionVarMethod)] = method
+	env[string(authorizer.ExpressionVarPeer)] = params.Peer.Map()
+	env[string(authorizer.ExpressionVarDeadline)] = deadline
+	env[string(authorizer.ExpressionVarNow)] = now
+	if e.maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.maxDuration)
+		defer cancel()
+	}
+	if err := authorizer.EvaluateRuleSet(rules, decision, authorizer.TraceRuleEvaluator(ctx, e.tracer, Engine+".EvaluateRule", rules, func(i int) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, fmt.Errorf("authorizer: expression evaluation interrupted: %w", err)
+		}
+		machine := vm.VM{MemoryBudget: e.memoryBudget}
+		v, err := machine.Run(programs[i], env)
+		if err != nil {
+			return false, fmt.Errorf("authorizer: failed to run expression: %v", err.Error())
+		}
+		pass, ok := v.(bool)
+		if !ok {
+			return false, fmt.Errorf("authorizer: expression did not return a boolean")
+		}
+		return pass, nil
+	})); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+func (e *ExprAuthorizer) getMethodPrograms(rules *authorize.RuleSet) ([]*vm.Program, error) {
+	var programs []*vm.Program
+	for _, rule := range rules.Rules {
+		program, err := e.getProgram(rule)
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, program)
+	}
+	return programs, nil
+}
+
+// getProgram returns the compiled program of the rule's expression, or nil for the wildcard expression
+func (e *ExprAuthorizer) getProgram(rule *authorize.Rule) (*vm.Program, error) {
+	if rule.Expression == authorizer.WildcardExpression {
+		return nil, nil
+	}
+	if program, ok := e.cachedPrograms.Load(rule.Expression); ok {
+		return program.(*vm.Program), nil
+	}
+	env := Variables()
+	for k, v := range e.variables {
+		if v == nil {
+			env[k] = types.Any
+			continue
+		}
+		env[k] = types.TypeOf(v)
+	}
+	program, err := exprlang.Compile(rule.Expression, append([]exprlang.Option{exprlang.Env(env), exprlang.AsBool()}, e.options...)...)
+	if err != nil {
+		return nil, fmt.Errorf("authorizer: failed to compile expression: %v", err.Error())
+	}
+	e.cachedPrograms.Store(rule.Expression, program)
+	return program, nil
+}
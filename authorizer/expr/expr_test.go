@@ -0,0 +1,613 @@
+package expr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	exprlang "github.com/expr-lang/expr"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+
+	"github.com/autom8ter/protoc-gen-authorize/authorizer"
+	"github.com/autom8ter/protoc-gen-authorize/authorizer/expr"
+	"github.com/autom8ter/protoc-gen-authorize/example/gen/example"
+)
+
+type fixture struct {
+	name        string
+	method      string
+	opts        []expr.Opt
+	rules       map[string]*authorize.RuleSet
+	params      *authorizer.RuleExecutionParams
+	expectError bool
+	expectAllow bool
+}
+
+type Request struct {
+	StrVal    string
+	StrsVal   []string
+	Int64Val  int64
+	Ints64Val []int64
+	FloatVal  float64
+	FloatsVal []float64
+	BoolVal   bool
+	BoolsVal  []bool
+}
+
+func (r *Request) GetStrVal() string {
+	return r.StrVal
+}
+
+func (r *Request) GetStrsVal() []string {
+	return r.StrsVal
+}
+
+type User struct {
+	Roles       []string
+	IsSuperUser bool
+	Accounts    []string
+}
+
+var fixtures = []fixture{
+	{
+		name:   "basic request field rule 1 (allow)",
+		method: "testing",
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "request.GetStrVal() == 'hello' && request.Int64Val == 1",
+					},
+				},
+			},
+		},
+		params: &authorizer.RuleExecutionParams{
+			Request: &Request{
+				StrVal:   "hello",
+				Int64Val: 1,
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "basic user expression rule 1 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles: []string{"admin"},
+			},
+			Request: &Request{},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "'admin' in user.Roles",
+					},
+				},
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "basic user expression rule 2 (deny)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles: []string{"guest"},
+			},
+			Request: &Request{},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "'admin' in user.Roles",
+					},
+				},
+			},
+		},
+		expectAllow: false,
+	},
+	{
+		name:   "request and user list rule (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Accounts: []string{"1", "2"},
+			},
+			Request: &Request{
+				StrsVal: []string{"1", "2"},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "all(request.GetStrsVal(), # in user.Accounts)",
+					},
+				},
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "metadata rule (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			Request:  &Request{},
+			Metadata: map[string][]string{"x-account-id": {"1"}, "x-tags": {"a", "b"}},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "metadata['x-account-id'] == '1' && metadata['x-tags'] == 'a,b' && len(metadata_values['x-tags']) == 2",
+					},
+				},
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "deny rule (deny)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles:       []string{"admin"},
+				IsSuperUser: true,
+			},
+			Request: &Request{},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+				Rules: []*authorize.Rule{
+					{
+						Expression: "'admin' in user.Roles",
+					},
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+				},
+			},
+		},
+		expectAllow: false,
+	},
+	{
+		name:   "custom variables (allow)",
+		method: "testing",
+		opts: []expr.Opt{expr.WithVariables(map[string]any{
+			"isAdmin": func(user *User) bool {
+				return user.IsSuperUser
+			},
+		})},
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				IsSuperUser: true,
+			},
+			Request: &Request{},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "isAdmin(user)",
+					},
+				},
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "non boolean expression (error)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			Request: &Request{
+				StrVal: "hello",
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "request.StrVal",
+					},
+				},
+			},
+		},
+		expectError: true,
+	},
+}
+
+func TestExprAuthorizer_AuthorizeMethod(t *testing.T) {
+	ctx := context.Background()
+	for _, fix := range fixtures {
+		t.Run(fix.name, func(t *testing.T) {
+			if fix.method == "" {
+				t.Fatalf("method is required")
+			}
+			authz, err := expr.NewExprAuthorizer(fix.rules, fix.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			allow, err := authz.AuthorizeMethod(ctx, fix.method, fix.params)
+			if fix.expectError {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if fix.expectAllow {
+				if !allow {
+					t.Fatalf("expected allow")
+				}
+			} else {
+				if allow {
+					t.Fatalf("expected deny")
+				}
+			}
+		})
+	}
+}
+
+func TestExprAuthorizer_Decide(t *testing.T) {
+	authz, err := expr.NewExprAuthorizer(map[string]*authorize.RuleSet{
+		"testing": {
+			Rules: []*authorize.Rule{
+				{
+					Expression: "user.IsSuperUser",
+				},
+				{
+					Expression: "'admin' in user.Roles",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decision, err := authz.Decide(context.Background(), "testing", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow")
+	}
+	if decision.RuleIndex != 1 || decision.RulesEvaluated != 2 || decision.Engine != expr.Engine {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+	decision, err = authz.Decide(context.Background(), "testing", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"guest"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decision.Allowed() {
+		t.Fatalf("expected deny")
+	}
+	if decision.RuleIndex != -1 || decision.Reason == "" {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+}
+
+func TestExprAuthorizer_MissingRules(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "false"}},
+		},
+	}
+	type test struct {
+		policy      authorizer.MissingRulesPolicy
+		method      string
+		expectError bool
+		expectAllow bool
+	}
+	tests := []test{
+		{policy: "", method: "/example.ExampleService/MetadataMatch", expectAllow: true},
+		{policy: "", method: "/example.ExampleServiceV2/MetadataMatch", expectAllow: false},
+		{policy: authorizer.MissingRulesDeny, method: "/example.ExampleService/MetadataMatch", expectAllow: false},
+		{policy: authorizer.MissingRulesAllow, method: "/other.OtherService/Method", expectAllow: true},
+		{policy: authorizer.MissingRulesError, method: "/example.ExampleService/MetadataMatch", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.policy, tt.method), func(t *testing.T) {
+			authz, err := expr.NewExprAuthorizer(rules, expr.WithMissingRulesPolicy(tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			decision, err := authz.Decide(context.Background(), tt.method, &authorizer.RuleExecutionParams{})
+			if tt.expectError {
+				if !errors.Is(err, authorizer.ErrMissingRules) {
+					t.Fatalf("expected missing rules error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decision.Allowed() != tt.expectAllow {
+				t.Fatalf("expected allow=%v, got %+v", tt.expectAllow, decision)
+			}
+		})
+	}
+}
+
+func TestExprAuthorizer_RequestInfo(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "method startsWith '/example.' && peer.ip == '127.0.0.1' && peer.tls == nil && deadline != nil && deadline > now && now.Year() >= 2020 && !is_stream"}},
+		},
+		"/example.ExampleService/MetadataMatch": {
+			Rules: []*authorize.Rule{{Expression: "deadline == nil && peer.address == ''"}},
+		},
+	}
+	authz, err := expr.NewExprAuthorizer(rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decision, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		Request:  &example.Request{},
+		Peer:     authorizer.NewPeer("127.0.0.1:52342"),
+		Deadline: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow: %+v", decision)
+	}
+	// requests without a peer or deadline
+	decision, err = authz.Decide(context.Background(), "/example.ExampleService/MetadataMatch", &authorizer.RuleExecutionParams{
+		Request: &example.Request{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow: %+v", decision)
+	}
+}
+
+func TestExprAuthorizer_WithOptions(t *testing.T) {
+	authz, err := expr.NewExprAuthorizer(map[string]*authorize.RuleSet{
+		"testing": {
+			Rules: []*authorize.Rule{{Expression: "isInternal(metadata['x-team'])"}},
+		},
+	}, expr.WithOptions(exprlang.Function("isInternal", func(params ...any) (any, error) {
+		return params[0] == "platform", nil
+	}, new(func(string) bool))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for team, expectAllow := range map[string]bool{"platform": true, "sales": false} {
+		allow, err := authz.AuthorizeMethod(context.Background(), "testing", &authorizer.RuleExecutionParams{
+			Metadata: map[string][]string{"x-team": {team}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if allow != expectAllow {
+			t.Fatalf("expected allow=%v for team %s", expectAllow, team)
+		}
+	}
+}
+
+func TestLoadExprAuthorizer(t *testing.T) {
+	dir := t.TempDir()
+	policy := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(policy, []byte(`
+/example.ExampleService/RequestMatch:
+  algorithm: COMBINING_ALGORITHM_DENY_OVERRIDES
+  rules:
+    - expression: "user.IsSuperUser"
+      effect: EFFECT_DENY
+    - expression: "'admin' in user.Roles"
+`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authz, err := expr.LoadExprAuthorizer(policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decision, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() || decision.RuleIndex != 1 {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{
+		"/example.ExampleService/RequestMatch": {"rules": [{"expression": "'admin' in user.Roles &&"}]}
+	}`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := expr.LoadExprAuthorizer(invalid); err == nil || !strings.Contains(err.Error(), "/example.ExampleService/RequestMatch") {
+		t.Fatalf("expected compile error for method, got %v", err)
+	}
+}
+
+func TestExprAuthorizer_Validate(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "'admin' in user.Roles"}, {Expression: "'admin' in"}},
+		},
+		"/example.ExampleService/MetadataMatch": {
+			Rules: []*authorize.Rule{{Expression: "method + 1"}},
+		},
+		"/example.ExampleService/AllowAll": {
+			Rules: []*authorize.Rule{{Expression: authorizer.WildcardExpression}},
+		},
+	}
+	_, err := expr.NewExprAuthorizer(rules)
+	if err == nil {
+		t.Fatalf("expected compile error")
+	}
+	// every invalid expression is reported
+	for _, expected := range []string{"/example.ExampleService/MetadataMatch: rule 0:", "/example.ExampleService/RequestMatch: rule 1:"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+	authz, err := expr.NewExprAuthorizer(rules, expr.WithLazyCompilation())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := authz.Validate(); err == nil {
+		t.Fatalf("expected compile error")
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/MetadataMatch", &authorizer.RuleExecutionParams{}); err == nil {
+		t.Fatalf("expected compile error at request time")
+	}
+	decision, err := authz.Decide(context.Background(), "/example.ExampleService/AllowAll", &authorizer.RuleExecutionParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow: %+v", decision)
+	}
+}
+
+func TestExprAuthorizer_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	authz, err := expr.NewExprAuthorizer(map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "request.AccountId == 'other'"}, {Expression: "true"}},
+		},
+	}, expr.WithTracerProvider(provider))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		Request: &example.Request{AccountId: "123"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected a decision span and 2 rule spans, got %d", len(spans))
+	}
+	// child spans end before their parent
+	decide := spans[2]
+	if decide.Name != expr.Engine+".Decide" {
+		t.Fatalf("expected decision span, got %s", decide.Name)
+	}
+	attributes := attribute.NewSet(decide.Attributes...)
+	for key, expected := range map[attribute.Key]any{
+		authorizer.AttributeMethod:    "/example.ExampleService/RequestMatch",
+		authorizer.AttributeDecision:  string(authorizer.EffectAllow),
+		authorizer.AttributeEngine:    expr.Engine,
+		authorizer.AttributeRuleIndex: int64(1),
+	} {
+		if v, ok := attributes.Value(key); !ok || v.AsInterface() != expected {
+			t.Fatalf("expected %s to be %v, got %v", key, expected, v.AsInterface())
+		}
+	}
+	for i, span := range spans[:2] {
+		if span.Name != expr.Engine+".EvaluateRule" || span.Parent.SpanID() != decide.SpanContext.SpanID() {
+			t.Fatalf("expected rule span to be a child of the decision span, got %s", span.Name)
+		}
+		attributes := attribute.NewSet(span.Attributes...)
+		if v, _ := attributes.Value(authorizer.AttributeRuleIndex); v.AsInt64() != int64(i) {
+			t.Fatalf("expected rule index %d, got %v", i, v.AsInt64())
+		}
+		if v, _ := attributes.Value(authorizer.AttributeRuleResult); v.AsBool() != (i == 1) {
+			t.Fatalf("expected rule %d result %v, got %v", i, i == 1, v.AsBool())
+		}
+	}
+}
+
+func TestExprAuthorizer_Limits(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "true"}},
+		},
+	}
+	authz, err := expr.NewExprAuthorizer(rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// rules are not evaluated once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := authz.Decide(ctx, "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected interrupted evaluation, got %v", err)
+	}
+	// a single expression can't allocate more than the memory budget
+	rules = map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "len(filter(1..100000, # % 2 == 0)) > 0"}},
+		},
+	}
+	authz, err = expr.NewExprAuthorizer(rules, expr.WithMemoryBudget(1000))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{}); err == nil || !strings.Contains(err.Error(), "memory budget exceeded") {
+		t.Fatalf("expected memory budget error, got %v", err)
+	}
+	authz, err = expr.NewExprAuthorizer(rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if allow, err := authz.AuthorizeMethod(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{}); err != nil || !allow {
+		t.Fatalf("expected allow, got %v %v", allow, err)
+	}
+}
+
+func BenchmarkExprAuthorizer_AuthorizeMethod(b *testing.B) {
+	ctx := context.Background()
+	for _, fix := range fixtures {
+		b.Run(fix.name, func(b *testing.B) {
+			if fix.method == "" {
+				b.Fatalf("method is required")
+			}
+			authz, err := expr.NewExprAuthorizer(fix.rules, fix.opts...)
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				allow, err := authz.AuthorizeMethod(ctx, fix.method, fix.params)
+				if fix.expectError {
+					if err == nil {
+						b.Fatalf("expected error")
+					}
+				} else {
+					if err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+				}
+				if allow != fix.expectAllow {
+					b.Fatalf("expected allow=%v", fix.expectAllow)
+				}
+			}
+		})
+	}
+}
@@ -2,17 +2,24 @@ package javascript
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/dop251/goja"
+	"go.opentelemetry.io/otel/trace"
 
-	"github.com/autom8ter/proto/gen/authorize"
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 
 	"github.com/autom8ter/protoc-gen-authorize/authorizer"
 )
 
+// Engine is the name of the javascript expression engine reported in authorization decisions
+const Engine = "javascript"
+
 // Opt is a functional option for configuring a JavascriptAuthorizer
 type Opt func(*JavascriptAuthorizer)
 
@@ -25,16 +32,68 @@ func WithVariables(variables map[string]any) Opt {
 	}
 }
 
+// WithMissingRulesPolicy sets how requests to methods that have no rules are authorized.
+// Defaults to authorizer.MissingRulesAllowIfServiceAnnotated
+func WithMissingRulesPolicy(policy authorizer.MissingRulesPolicy) Opt {
+	return func(a *JavascriptAuthorizer) {
+		a.missingRules = policy
+	}
+}
+
+// WithTracerProvider sets the tracer provider used to record a span for every decision, with a child span for every
+// rule expression that is evaluated
+func WithTracerProvider(provider trace.TracerProvider) Opt {
+	return func(a *JavascriptAuthorizer) {
+		a.tracer = provider.Tracer(authorizer.TracerName)
+	}
+}
+
+// WithMaxDuration sets the maximum time the rule expressions of a request may run before they are interrupted.
+// Expressions are always interrupted when the context of the request is done (ex: its deadline is exceeded).
+// Defaults to no limit
+func WithMaxDuration(maxDuration time.Duration) Opt {
+	return func(a *JavascriptAuthorizer) {
+		a.maxDuration = maxDuration
+	}
+}
+
+// WithLazyCompilation defers the compilation of rule expressions to the first request of their method instead of
+// compiling every expression in NewJavascriptAuthorizer. Invalid expressions are then only reported by Validate or at request time
+func WithLazyCompilation() Opt {
+	return func(j *JavascriptAuthorizer) {
+		j.lazy = true
+	}
+}
+
+// WithRuntimePool enables or disables pooling of javascript runtimes. Pooled runtimes are created with the variables of
+// WithVariables installed. Every expression is evaluated in its own block scope and the globals an expression defines
+// are removed before its runtime is reused, but the built-in objects (ex: Array.prototype) are shared between requests,
+// so expressions that modify them require disabling the pool. Defaults to enabled
+func WithRuntimePool(enabled bool) Opt {
+	return func(a *JavascriptAuthorizer) {
+		a.disableRuntimePool = !enabled
+	}
+}
+
 // JavascriptAuthorizer is a javascript vm that uses javascript expressions to authorize grpc requests
 type JavascriptAuthorizer struct {
 	rules          map[string]*authorize.RuleSet
 	cachedPrograms sync.Map
 	variables      map[string]any
+	missingRules   authorizer.MissingRulesPolicy
+	tracer         trace.Tracer
+	maxDuration    time.Duration
+	lazy           bool
+	// runtimes is a pool of runtimes with the variables installed, or nil if pooling is disabled
+	runtimes           *sync.Pool
+	disableRuntimePool bool
 }
 
 // NewJavascriptAuthorizer returns a new JavascriptAuthorizer. The rules map is a map of method names to RuleSets. The RuleSets are used to
-// authorize the method. The RuleSets are evaluated in order and the first rule that evaluates to true will authorize
-// the request. The mapping can be generated with the protoc-gen-authorize plugin.
+// authorize the method. The rules of a RuleSet are combined using the RuleSet's combining algorithm - by default,
+// the effect of the first rule that evaluates to true is applied to the request. The mapping can be generated with the protoc-gen-authorize plugin.
+// Every rule expression is compiled up front and an error describing each expression that fails to compile is returned,
+// unless WithLazyCompilation is set.
 func NewJavascriptAuthorizer(rules map[string]*authorize.RuleSet, opts ...Opt) (*JavascriptAuthorizer, error) {
 	a := &JavascriptAuthorizer{
 		rules:          rules,
@@ -44,37 +103,129 @@ func NewJavascriptAuthorizer(rules map[string]*authorize.RuleSet, opts ...Opt) (
 	for _, opt := range opts {
 		opt(a)
 	}
+	// the variables are installed in a runtime up front so invalid variables fail fast
+	vm, err := a.newRuntime()
+	if err != nil {
+		return nil, err
+	}
+	if !a.disableRuntimePool {
+		a.runtimes = &sync.Pool{}
+		a.runtimes.Put(vm)
+	}
+	if !a.lazy {
+		if err := a.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// LoadJavascriptAuthorizer loads a YAML or JSON policy document of method names to RuleSets (see authorizer.ParsePolicy) and returns
+// a new JavascriptAuthorizer for it. The policy is rejected if any of its expressions fail to compile
+func LoadJavascriptAuthorizer(path string, opts ...Opt) (*JavascriptAuthorizer, error) {
+	rules, err := authorizer.LoadPolicy(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := NewJavascriptAuthorizer(rules, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if a.lazy {
+		if err := a.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	return a, nil
 }
 
+// Factory returns an authorizer.AuthorizerFactory that creates a JavascriptAuthorizer with the given options, for example
+// to hot reload a policy with authorizer.NewReloadingAuthorizer
+func Factory(opts ...Opt) authorizer.AuthorizerFactory {
+	return func(rules map[string]*authorize.RuleSet) (authorizer.Authorizer, error) {
+		return NewJavascriptAuthorizer(rules, opts...)
+	}
+}
+
+// Validate compiles the expressions of every method's rules and returns an error describing each expression that fails to compile
+func (a *JavascriptAuthorizer) Validate() error {
+	methods := make([]string, 0, len(a.rules))
+	for method := range a.rules {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	var errs []error
+	for _, method := range methods {
+		for i, rule := range a.rules[method].GetRules() {
+			if _, err := a.getProgram(rule); err != nil {
+				errs = append(errs, fmt.Errorf("%s: rule %d: %w", method, i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RuleSet returns the RuleSet of the given method
+func (a *JavascriptAuthorizer) RuleSet(method string) (*authorize.RuleSet, bool) {
+	rules, ok := a.rules[method]
+	return rules, ok
+}
+
 // AuthorizeMethod authorizes a gRPC method the RuleExecutionParams and returns a boolean representing whether the
 // request is authorized or not.
 func (a *JavascriptAuthorizer) AuthorizeMethod(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (bool, error) {
-	// return false if no rules exist for the method
+	decision, err := a.Decide(ctx, method, params)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed(), nil
+}
+
+// Decide authorizes a gRPC method the RuleExecutionParams and returns a Decision describing which rule
+// authorized the request, or why it was denied.
+func (a *JavascriptAuthorizer) Decide(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (*authorizer.Decision, error) {
+	return authorizer.TraceDecision(ctx, a.tracer, Engine+".Decide", method, params, a.decide)
+}
+
+func (a *JavascriptAuthorizer) decide(ctx context.Context, method string, params *authorizer.RuleExecutionParams) (*authorizer.Decision, error) {
+	start := time.Now()
+	decision := &authorizer.Decision{
+		Effect:    authorizer.EffectDeny,
+		RuleIndex: -1,
+		Engine:    Engine,
+	}
+	defer func() {
+		decision.EvaluationTime = time.Since(start)
+	}()
+	// deny if no rules exist for the method
 	rules, ok := a.rules[method]
 	if !ok {
-		svc := strings.Split(method, "/")[1]
-		for k, _ := range a.rules {
-			if strings.HasPrefix(k, "/"+svc) {
-				return true, nil
-			}
+		if err := authorizer.DecideMissingRules(a.missingRules, a.rules, method, decision); err != nil {
+			return nil, err
 		}
-		return false, nil
+		return decision, nil
 	}
-	// allow all
-	if len(rules.Rules) == 1 && rules.Rules[0].Expression == "*" {
-		return true, nil
+	if len(rules.Rules) == 1 && rules.Rules[0].Expression == authorizer.WildcardExpression {
+		if err := authorizer.EvaluateRuleSet(rules, decision, nil); err != nil {
+			return nil, err
+		}
+		return decision, nil
 	}
 	programs, err := a.getMethodPrograms(rules)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	vm := goja.New()
-	for k, v := range a.variables {
-		if err := vm.Set(k, v); err != nil {
-			return false, fmt.Errorf("authorizer: failed to set variable: %v", err.Error())
-		}
+	vm, err := a.getRuntime()
+	if err != nil {
+		return nil, err
 	}
+	interrupted := false
+	defer func() {
+		// interrupted runtimes are discarded in case they were stopped in an inconsistent state
+		if !interrupted {
+			a.putRuntime(vm)
+		}
+	}()
 	var (
 		metaMap = map[string]string{}
 	)
@@ -82,47 +233,173 @@ func (a *JavascriptAuthorizer) AuthorizeMethod(ctx context.Context, method strin
 		metaMap[k] = strings.Join(v, ",")
 	}
 	if err := vm.Set(string(authorizer.ExpressionVarMetadata), metaMap); err != nil {
-		return false, fmt.Errorf("authorizer: failed to set metadata: %v", err.Error())
+		return nil, fmt.Errorf("authorizer: failed to set metadata: %v", err.Error())
+	}
+	metadataValues := map[string][]any{}
+	for k, values := range authorizer.MetadataValues(params.Metadata) {
+		for i, v := range values {
+			// binary header values are exposed as Uint8Arrays
+			if b, ok := v.([]byte); ok {
+				array, err := vm.New(vm.Get("Uint8Array"), vm.ToValue(vm.NewArrayBuffer(b)))
+				if err != nil {
+					return nil, fmt.Errorf("authorizer: failed to set metadata_values: %v", err.Error())
+				}
+				values[i] = array
+			}
+		}
+		metadataValues[k] = values
+	}
+	if err := vm.Set(string(authorizer.ExpressionVarMetadataValues), metadataValues); err != nil {
+		return nil, fmt.Errorf("authorizer: failed to set metadata_values: %v", err.Error())
 	}
 	if err := vm.Set(string(authorizer.ExpressionVarRequest), params.Request); err != nil {
-		return false, fmt.Errorf("authorizer: failed to set request: %v", err.Error())
+		return nil, fmt.Errorf("authorizer: failed to set request: %v", err.Error())
 	}
 	if err := vm.Set(string(authorizer.ExpressionVarUser), params.User); err != nil {
-		return false, fmt.Errorf("authorizer: failed to set user: %v", err.Error())
+		return nil, fmt.Errorf("authorizer: failed to set user: %v", err.Error())
 	}
 	if err := vm.Set(string(authorizer.ExpressionVarIsStream), params.IsStream); err != nil {
-		return false, fmt.Errorf("authorizer: failed to set is_stream: %v", err.Error())
+		return nil, fmt.Errorf("authorizer: failed to set is_stream: %v", err.Error())
 	}
 	if err := vm.Set(string(authorizer.ExpressionVarMethod), method); err != nil {
-		return false, fmt.Errorf("authorizer: failed to set method: %v", err.Error())
+		return nil, fmt.Errorf("authorizer: failed to set method: %v", err.Error())
+	}
+	if err := vm.Set(string(authorizer.ExpressionVarMessageIndex), params.MessageIndex); err != nil {
+		return nil, fmt.Errorf("authorizer: failed to set message_index: %v", err.Error())
+	}
+	if err := vm.Set(string(authorizer.ExpressionVarPeer), params.Peer.Map()); err != nil {
+		return nil, fmt.Errorf("authorizer: failed to set peer: %v", err.Error())
+	}
+	now := params.Now
+	if now.IsZero() {
+		now = start
 	}
-	for _, program := range programs {
-		v, err := vm.RunProgram(program)
+	for name, t := range map[authorizer.ExpressionVar]time.Time{
+		authorizer.ExpressionVarNow:      now,
+		authorizer.ExpressionVarDeadline: params.Deadline,
+	} {
+		date, err := newDate(vm, t)
 		if err != nil {
+			return nil, fmt.Errorf("authorizer: failed to set %s: %v", name, err.Error())
+		}
+		if err := vm.Set(string(name), date); err != nil {
+			return nil, fmt.Errorf("authorizer: failed to set %s: %v", name, err.Error())
+		}
+	}
+	if a.maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.maxDuration)
+		defer cancel()
+	}
+	// pathological expressions (ex: while(true){}) are interrupted when the context is done
+	stop := context.AfterFunc(ctx, func() {
+		vm.Interrupt(ctx.Err())
+	})
+	defer func() {
+		// the runtime is discarded if the interrupt may have been delivered after the evaluation
+		if !stop() {
+			interrupted = true
+		}
+	}()
+	if err := authorizer.EvaluateRuleSet(rules, decision, authorizer.TraceRuleEvaluator(ctx, a.tracer, Engine+".EvaluateRule", rules, func(i int) (bool, error) {
+		v, err := vm.RunProgram(programs[i])
+		if err != nil {
+			var interruptedErr *goja.InterruptedError
+			if errors.As(err, &interruptedErr) {
+				interrupted = true
+				return false, fmt.Errorf("authorizer: expression evaluation interrupted: %w", ctx.Err())
+			}
 			return false, fmt.Errorf("authorizer: failed to run expression: %v", err.Error())
 		}
-		if v.ToBoolean() {
-			return true, nil
+		return v.ToBoolean(), nil
+	})); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+// newRuntime returns a new runtime with the variables installed
+func (a *JavascriptAuthorizer) newRuntime() (*goja.Runtime, error) {
+	vm := goja.New()
+	for k, v := range a.variables {
+		if err := vm.Set(k, v); err != nil {
+			return nil, fmt.Errorf("authorizer: failed to set variable: %v", err.Error())
 		}
 	}
-	return false, nil
+	return vm, nil
+}
+
+// getRuntime returns a runtime from the pool, or a new runtime if the pool is empty or disabled
+func (a *JavascriptAuthorizer) getRuntime() (*goja.Runtime, error) {
+	if a.runtimes != nil {
+		if vm, ok := a.runtimes.Get().(*goja.Runtime); ok {
+			return vm, nil
+		}
+	}
+	return a.newRuntime()
+}
+
+// putRuntime removes the request variables and the globals defined by expressions, so idle runtimes don't hold the data
+// of the last request, reinstalls the variables and returns a runtime to the pool. Runtimes that fail to reset are discarded
+func (a *JavascriptAuthorizer) putRuntime(vm *goja.Runtime) {
+	if a.runtimes == nil {
+		return
+	}
+	global := vm.GlobalObject()
+	for _, key := range global.Keys() {
+		if _, ok := a.variables[key]; ok {
+			continue
+		}
+		// globals declared with var can't be deleted, so their values are cleared instead
+		if err := global.Delete(key); err != nil {
+			if err := global.Set(key, goja.Undefined()); err != nil {
+				return
+			}
+		}
+	}
+	for k, v := range a.variables {
+		if err := vm.Set(k, v); err != nil {
+			return
+		}
+	}
+	vm.ClearInterrupt()
+	a.runtimes.Put(vm)
+}
+
+// newDate returns a javascript Date of the given time, or null if the time is zero
+func newDate(vm *goja.Runtime, t time.Time) (goja.Value, error) {
+	if t.IsZero() {
+		return goja.Null(), nil
+	}
+	return vm.New(vm.Get("Date"), vm.ToValue(t.UnixMilli()))
 }
 
 func (j *JavascriptAuthorizer) getMethodPrograms(rules *authorize.RuleSet) ([]*goja.Program, error) {
-	var (
-		programs []*goja.Program
-		err      error
-	)
+	var programs []*goja.Program
 	for _, rule := range rules.Rules {
-		program, ok := j.cachedPrograms.Load(rule.Expression)
-		if !ok {
-			program, err = goja.Compile(rule.Expression, rule.Expression, true)
-			if err != nil {
-				return nil, fmt.Errorf("authorizer: failed to compile expression: %v", err.Error())
-			}
-			j.cachedPrograms.Store(rule.Expression, program)
+		program, err := j.getProgram(rule)
+		if err != nil {
+			return nil, err
 		}
-		programs = append(programs, program.(*goja.Program))
+		programs = append(programs, program)
 	}
 	return programs, nil
 }
+
+// getProgram returns the compiled program of the rule's expression, or nil for the wildcard expression
+func (j *JavascriptAuthorizer) getProgram(rule *authorize.Rule) (*goja.Program, error) {
+	if rule.Expression == authorizer.WildcardExpression {
+		return nil, nil
+	}
+	if program, ok := j.cachedPrograms.Load(rule.Expression); ok {
+		return program.(*goja.Program), nil
+	}
+	// the expression is evaluated in a block so its let, const and function declarations don't leak into the runtime.
+	// The value of the block is the value of its last statement
+	program, err := goja.Compile(rule.Expression, "{"+rule.Expression+"\n}", true)
+	if err != nil {
+		return nil, fmt.Errorf("authorizer: failed to compile expression: %v", err.Error())
+	}
+	j.cachedPrograms.Store(rule.Expression, program)
+	return program, nil
+}
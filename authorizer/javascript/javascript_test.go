@@ -2,12 +2,25 @@ package javascript_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/autom8ter/proto/gen/authorize"
+	"github.com/dop251/goja"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 
 	"github.com/autom8ter/protoc-gen-authorize/authorizer"
 	"github.com/autom8ter/protoc-gen-authorize/authorizer/javascript"
+	"github.com/autom8ter/protoc-gen-authorize/example/gen/example"
 )
 
 type fixture struct {
@@ -308,6 +321,167 @@ var fixtures = []fixture{
 		},
 		expectAllow: true,
 	},
+	{
+		name:   "first applicable deny rule 10 (deny)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles:       []string{"admin"},
+				IsSuperUser: true,
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+					{
+						Expression: "user.Roles.includes('admin')",
+					},
+				},
+			},
+		},
+		expectAllow: false,
+	},
+	{
+		name:   "deny overrides rule 11 (deny)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles:       []string{"admin"},
+				IsSuperUser: true,
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "user.Roles.includes('admin')",
+					},
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+				},
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+			},
+		},
+		expectAllow: false,
+	},
+	{
+		name:   "deny overrides rule 12 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles: []string{"admin"},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "user.Roles.includes('admin')",
+					},
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+				},
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "permit overrides rule 13 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles:       []string{"admin"},
+				IsSuperUser: true,
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+					{
+						Expression: "user.Roles.includes('admin')",
+					},
+				},
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_PERMIT_OVERRIDES,
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "wildcard fallback rule 14 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			User: &User{
+				Roles: []string{"guest"},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "user.IsSuperUser",
+						Effect:     authorize.Effect_EFFECT_DENY,
+					},
+					{
+						Expression: "*",
+					},
+				},
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "metadata values rule 15 (allow)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			Metadata: map[string][]string{
+				"x-role":    {"user", "admin"},
+				"x-tags":    {"a,b", "c"},
+				"trace-bin": {string([]byte{1, 2})},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "metadata_values['x-role'].includes('admin') && metadata_values['x-tags'].includes('a,b') && metadata_values['x-tags'].length === 2 && metadata_values['trace-bin'][0][1] === 2",
+					},
+				},
+			},
+		},
+		expectAllow: true,
+	},
+	{
+		name:   "metadata values rule 16 (deny)",
+		method: "testing",
+		params: &authorizer.RuleExecutionParams{
+			Metadata: map[string][]string{
+				"x-role": {"user", "admin"},
+			},
+		},
+		rules: map[string]*authorize.RuleSet{
+			"testing": {
+				Rules: []*authorize.Rule{
+					{
+						Expression: "metadata['x-role'] === 'admin' && metadata_values['x-role'].includes('admin')",
+					},
+				},
+			},
+		},
+		expectAllow: false,
+	},
 }
 
 func TestJavascriptAuthorizer_AuthorizeMethod(t *testing.T) {
@@ -351,6 +525,418 @@ func TestJavascriptAuthorizer_AuthorizeMethod(t *testing.T) {
 	}
 }
 
+func TestJavascriptAuthorizer_Decide(t *testing.T) {
+	authz, err := javascript.NewJavascriptAuthorizer(map[string]*authorize.RuleSet{
+		"testing": {
+			Rules: []*authorize.Rule{
+				{
+					Expression: "user.IsSuperUser",
+				},
+				{
+					Name:       "admins",
+					Expression: "user.Roles.includes('admin')",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decision, err := authz.Decide(context.Background(), "testing", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow")
+	}
+	if decision.RuleIndex != 1 || decision.RuleName != "admins" || decision.RulesEvaluated != 2 || decision.Engine != javascript.Engine {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+	decision, err = authz.Decide(context.Background(), "testing", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"guest"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decision.Allowed() {
+		t.Fatalf("expected deny")
+	}
+	if decision.RuleIndex != -1 || decision.Reason == "" {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+}
+
+func TestJavascriptAuthorizer_MissingRules(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "false"}},
+		},
+	}
+	type test struct {
+		policy      authorizer.MissingRulesPolicy
+		method      string
+		expectError bool
+		expectAllow bool
+	}
+	tests := []test{
+		{policy: "", method: "/example.ExampleService/MetadataMatch", expectAllow: true},
+		{policy: "", method: "/example.ExampleServiceV2/MetadataMatch", expectAllow: false},
+		{policy: authorizer.MissingRulesAllowIfServiceAnnotated, method: "/other.OtherService/Method", expectAllow: false},
+		{policy: authorizer.MissingRulesAllowIfServiceAnnotated, method: "missing", expectAllow: false},
+		{policy: authorizer.MissingRulesDeny, method: "/example.ExampleService/MetadataMatch", expectAllow: false},
+		{policy: authorizer.MissingRulesAllow, method: "/other.OtherService/Method", expectAllow: true},
+		{policy: authorizer.MissingRulesError, method: "/example.ExampleService/MetadataMatch", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.policy, tt.method), func(t *testing.T) {
+			authz, err := javascript.NewJavascriptAuthorizer(rules, javascript.WithMissingRulesPolicy(tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			decision, err := authz.Decide(context.Background(), tt.method, &authorizer.RuleExecutionParams{})
+			if tt.expectError {
+				if !errors.Is(err, authorizer.ErrMissingRules) {
+					t.Fatalf("expected missing rules error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decision.Allowed() != tt.expectAllow {
+				t.Fatalf("expected allow=%v, got %+v", tt.expectAllow, decision)
+			}
+		})
+	}
+}
+
+func TestJavascriptAuthorizer_RequestInfo(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "method.startsWith('/example.') && peer.ip === '127.0.0.1' && peer.tls === null && deadline !== null && deadline > now && now.getFullYear() >= 2020"}},
+		},
+		"/example.ExampleService/MetadataMatch": {
+			Rules: []*authorize.Rule{{Expression: "deadline === null && peer.address === ''"}},
+		},
+	}
+	authz, err := javascript.NewJavascriptAuthorizer(rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decision, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		Request:  &example.Request{},
+		Peer:     authorizer.NewPeer("127.0.0.1:52342"),
+		Deadline: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow: %+v", decision)
+	}
+	// requests without a peer or deadline
+	decision, err = authz.Decide(context.Background(), "/example.ExampleService/MetadataMatch", &authorizer.RuleExecutionParams{
+		Request: &example.Request{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow: %+v", decision)
+	}
+}
+
+func TestLoadJavascriptAuthorizer(t *testing.T) {
+	dir := t.TempDir()
+	policy := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(policy, []byte(`
+/example.ExampleService/RequestMatch:
+  algorithm: COMBINING_ALGORITHM_DENY_OVERRIDES
+  rules:
+    - expression: "user.IsSuperUser"
+      effect: EFFECT_DENY
+    - expression: "user.Roles.includes('admin')"
+`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authz, err := javascript.LoadJavascriptAuthorizer(policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decision, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		User: &User{
+			Roles: []string{"admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() || decision.RuleIndex != 1 {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{
+		"/example.ExampleService/RequestMatch": {"rules": [{"expression": "user.Roles.includes('admin') &&"}]}
+	}`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := javascript.LoadJavascriptAuthorizer(invalid); err == nil || !strings.Contains(err.Error(), "/example.ExampleService/RequestMatch") {
+		t.Fatalf("expected compile error for method, got %v", err)
+	}
+}
+
+func TestJavascriptAuthorizer_Validate(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "user.roles.includes('admin')"}, {Expression: "user.roles.includes("}},
+		},
+		"/example.ExampleService/MetadataMatch": {
+			Rules: []*authorize.Rule{{Expression: "request.accountId ==="}},
+		},
+		"/example.ExampleService/AllowAll": {
+			Rules: []*authorize.Rule{{Expression: authorizer.WildcardExpression}},
+		},
+	}
+	_, err := javascript.NewJavascriptAuthorizer(rules)
+	if err == nil {
+		t.Fatalf("expected compile error")
+	}
+	// every invalid expression is reported
+	for _, expected := range []string{"/example.ExampleService/MetadataMatch: rule 0:", "/example.ExampleService/RequestMatch: rule 1:"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+	if strings.Count(err.Error(), "rule ") != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authz, err := javascript.NewJavascriptAuthorizer(rules, javascript.WithLazyCompilation())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := authz.Validate(); err == nil {
+		t.Fatalf("expected compile error")
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/MetadataMatch", &authorizer.RuleExecutionParams{}); err == nil {
+		t.Fatalf("expected compile error at request time")
+	}
+	decision, err := authz.Decide(context.Background(), "/example.ExampleService/AllowAll", &authorizer.RuleExecutionParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decision.Allowed() {
+		t.Fatalf("expected allow: %+v", decision)
+	}
+}
+
+func TestJavascriptAuthorizer_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	authz, err := javascript.NewJavascriptAuthorizer(map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "request.AccountId === 'other'"}, {Expression: "true"}},
+		},
+	}, javascript.WithTracerProvider(provider))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		Request: &example.Request{AccountId: "123"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected a decision span and 2 rule spans, got %d", len(spans))
+	}
+	// child spans end before their parent
+	decide := spans[2]
+	if decide.Name != javascript.Engine+".Decide" {
+		t.Fatalf("expected decision span, got %s", decide.Name)
+	}
+	attributes := attribute.NewSet(decide.Attributes...)
+	for key, expected := range map[attribute.Key]any{
+		authorizer.AttributeMethod:    "/example.ExampleService/RequestMatch",
+		authorizer.AttributeDecision:  string(authorizer.EffectAllow),
+		authorizer.AttributeEngine:    javascript.Engine,
+		authorizer.AttributeRuleIndex: int64(1),
+	} {
+		if v, ok := attributes.Value(key); !ok || v.AsInterface() != expected {
+			t.Fatalf("expected %s to be %v, got %v", key, expected, v.AsInterface())
+		}
+	}
+	for i, span := range spans[:2] {
+		if span.Name != javascript.Engine+".EvaluateRule" || span.Parent.SpanID() != decide.SpanContext.SpanID() {
+			t.Fatalf("expected rule span to be a child of the decision span, got %s", span.Name)
+		}
+		attributes := attribute.NewSet(span.Attributes...)
+		if v, _ := attributes.Value(authorizer.AttributeRuleIndex); v.AsInt64() != int64(i) {
+			t.Fatalf("expected rule index %d, got %v", i, v.AsInt64())
+		}
+		if v, _ := attributes.Value(authorizer.AttributeRuleResult); v.AsBool() != (i == 1) {
+			t.Fatalf("expected rule %d result %v, got %v", i, i == 1, v.AsBool())
+		}
+	}
+}
+
+func TestJavascriptAuthorizer_Limits(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "while(true){}"}},
+		},
+	}
+	params := &authorizer.RuleExecutionParams{Request: &example.Request{}}
+	authz, err := javascript.NewJavascriptAuthorizer(rules, javascript.WithMaxDuration(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := authz.Decide(context.Background(), "/example.ExampleService/RequestMatch", params); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected interrupted evaluation, got %v", err)
+	}
+	// expressions are interrupted when the context is done
+	authz, err = javascript.NewJavascriptAuthorizer(rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := authz.Decide(ctx, "/example.ExampleService/RequestMatch", params); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected interrupted evaluation, got %v", err)
+	}
+}
+
+func TestJavascriptAuthorizer_RuntimePool(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "isAdmin(user) && request.AccountId === metadata['x-account-id']"}},
+		},
+	}
+	variables := map[string]any{
+		"isAdmin": func(user map[string]any) bool {
+			return user["role"] == "admin"
+		},
+	}
+	for _, pooled := range []bool{true, false} {
+		authz, err := javascript.NewJavascriptAuthorizer(rules, javascript.WithVariables(variables), javascript.WithRuntimePool(pooled))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// interrupted runtimes are not reused
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := authz.Decide(ctx, "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{Request: &example.Request{}}); err != nil && !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var wg sync.WaitGroup
+		errs := make(chan error, 100)
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				role := "admin"
+				if i%2 == 0 {
+					role = "user"
+				}
+				accountID := fmt.Sprint(i)
+				allow, err := authz.AuthorizeMethod(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+					User:     map[string]any{"role": role},
+					Request:  &example.Request{AccountId: accountID},
+					Metadata: map[string][]string{"x-account-id": {accountID}},
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+				// the request variables of concurrent requests must not leak into each other
+				if allow != (role == "admin") {
+					errs <- fmt.Errorf("request %d: expected allow=%v, got %v", i, role == "admin", allow)
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("pooled=%v: %v", pooled, err)
+		}
+	}
+}
+
+func TestJavascriptAuthorizer_RuntimePoolGlobals(t *testing.T) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "var leaked = 1; globalThis.assigned = 1; let scoped = 1; function declared() {} isAdmin = null; true"}},
+		},
+		"/example.ExampleService/StreamMatch": {
+			Rules: []*authorize.Rule{{Expression: "typeof leaked === 'undefined' && typeof assigned === 'undefined' && typeof scoped === 'undefined' && typeof declared === 'undefined' && typeof isAdmin === 'function'"}},
+		},
+	}
+	variables := map[string]any{
+		"isAdmin": func(user map[string]any) bool {
+			return user["role"] == "admin"
+		},
+	}
+	authz, err := javascript.NewJavascriptAuthorizer(rules, javascript.WithVariables(variables))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	params := &authorizer.RuleExecutionParams{Request: &example.Request{}}
+	for i := 0; i < 3; i++ {
+		// the declarations of the previous evaluation must not conflict with the next one
+		allow, err := authz.AuthorizeMethod(context.Background(), "/example.ExampleService/RequestMatch", params)
+		if err != nil || !allow {
+			t.Fatalf("evaluation %d: expected allow, got %v %v", i, allow, err)
+		}
+		// the globals defined by the previous evaluation must not be visible to the next one
+		allow, err = authz.AuthorizeMethod(context.Background(), "/example.ExampleService/StreamMatch", params)
+		if err != nil || !allow {
+			t.Fatalf("evaluation %d: expected the globals to be reset, got %v %v", i, allow, err)
+		}
+	}
+	// idle runtimes must not hold the variables of the last request
+	var runtime *goja.Runtime
+	authz, err = javascript.NewJavascriptAuthorizer(map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "capture() && request.AccountId === '1'"}},
+		},
+	}, javascript.WithVariables(map[string]any{
+		"capture": func(call goja.FunctionCall, vm *goja.Runtime) goja.Value {
+			runtime = vm
+			return vm.ToValue(true)
+		},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	allow, err := authz.AuthorizeMethod(context.Background(), "/example.ExampleService/RequestMatch", &authorizer.RuleExecutionParams{
+		User:     map[string]any{"role": "admin"},
+		Request:  &example.Request{AccountId: "1"},
+		Metadata: map[string][]string{"authorization": {"Bearer token"}},
+	})
+	if err != nil || !allow {
+		t.Fatalf("expected allow, got %v %v", allow, err)
+	}
+	for _, name := range []authorizer.ExpressionVar{
+		authorizer.ExpressionVarRequest,
+		authorizer.ExpressionVarUser,
+		authorizer.ExpressionVarMetadata,
+		authorizer.ExpressionVarMetadataValues,
+		authorizer.ExpressionVarPeer,
+	} {
+		if v := runtime.Get(string(name)); v != nil && !goja.IsUndefined(v) {
+			t.Fatalf("expected %s to be reset, got %v", name, v)
+		}
+	}
+	if v := runtime.Get("capture"); v == nil || goja.IsUndefined(v) {
+		t.Fatal("expected the variables to be kept")
+	}
+}
+
 /*
 goos: darwin
 goarch: amd64
@@ -424,3 +1010,44 @@ func BenchmarkJavascriptAuthorizer_AuthorizeMethod(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkJavascriptAuthorizer_RuntimePool(b *testing.B) {
+	rules := map[string]*authorize.RuleSet{
+		"/example.ExampleService/RequestMatch": {
+			Rules: []*authorize.Rule{{Expression: "hasRole(user, 'admin') && request.AccountId === metadata['x-account-id']"}},
+		},
+	}
+	variables := map[string]any{
+		"hasRole": func(user map[string]any, role string) bool {
+			return user["role"] == role
+		},
+		"tenants": []string{"a", "b", "c"},
+		"limits":  map[string]int{"requests": 100},
+	}
+	params := &authorizer.RuleExecutionParams{
+		User:     map[string]any{"role": "admin"},
+		Request:  &example.Request{AccountId: "123"},
+		Metadata: map[string][]string{"x-account-id": {"123"}},
+	}
+	for _, pooled := range []bool{true, false} {
+		b.Run(fmt.Sprintf("pooled=%v", pooled), func(b *testing.B) {
+			authz, err := javascript.NewJavascriptAuthorizer(rules, javascript.WithVariables(variables), javascript.WithRuntimePool(pooled))
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					allow, err := authz.AuthorizeMethod(context.Background(), "/example.ExampleService/RequestMatch", params)
+					if err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+					if !allow {
+						b.Fatalf("expected allow")
+					}
+				}
+			})
+		})
+	}
+}
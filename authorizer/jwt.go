@@ -0,0 +1,524 @@
+package authorizer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// JWTOpt is an option for configuring a JWTUserExtractor
+type JWTOpt func(j *JWTUserExtractor)
+
+// WithJWTKey adds a static verification key with the given key id (kid). The key must be an *rsa.PublicKey, *ecdsa.PublicKey,
+// ed25519.PublicKey or a []byte HMAC secret of at least 32 bytes
+func WithJWTKey(kid string, key any) JWTOpt {
+	return func(j *JWTUserExtractor) {
+		j.staticKeys = append(j.staticKeys, jwk{kid: kid, key: key})
+	}
+}
+
+// WithJWKSFile loads verification keys from a JSON Web Key Set file on disk, which is reloaded every refresh interval
+// (0 disables refreshing). If a refresh fails, the last loaded keys keep being used
+func WithJWKSFile(path string, refresh time.Duration) JWTOpt {
+	return func(j *JWTUserExtractor) {
+		j.jwksFile = path
+		j.refresh = refresh
+	}
+}
+
+// WithJWTIssuer requires the iss claim of tokens to be one of the given issuers
+func WithJWTIssuer(issuers ...string) JWTOpt {
+	return func(j *JWTUserExtractor) {
+		j.issuers = append(j.issuers, issuers...)
+	}
+}
+
+// WithJWTAudience requires the aud claim of tokens to contain one of the given audiences
+func WithJWTAudience(audiences ...string) JWTOpt {
+	return func(j *JWTUserExtractor) {
+		j.audiences = append(j.audiences, audiences...)
+	}
+}
+
+// WithJWTLeeway sets the clock skew tolerated when checking the exp and nbf claims. Defaults to 0
+func WithJWTLeeway(leeway time.Duration) JWTOpt {
+	return func(j *JWTUserExtractor) {
+		j.leeway = leeway
+	}
+}
+
+// WithJWTOptionalExpiry accepts tokens without an exp claim. By default, tokens must have an exp claim so that a leaked
+// token can't be used forever
+func WithJWTOptionalExpiry() JWTOpt {
+	return func(j *JWTUserExtractor) {
+		j.optionalExpiry = true
+	}
+}
+
+// WithJWTMetadataKey sets the metadata key that holds the bearer token. Defaults to authorization
+func WithJWTMetadataKey(key string) JWTOpt {
+	return func(j *JWTUserExtractor) {
+		j.metadataKey = strings.ToLower(key)
+	}
+}
+
+// JWTUserExtractor extracts users from JWT bearer tokens in the incoming metadata of grpc requests.
+// The signature of the token is verified with the configured keys (HS256/384/512, RS256/384/512, PS256/384/512,
+// ES256/384/512 and EdDSA are supported), and the exp, nbf, iss and aud claims are checked. The exp claim is required
+// unless WithJWTOptionalExpiry is set.
+// The claims of the token are returned as the user (a map[string]any), so expressions can reference them (ex: user.sub)
+type JWTUserExtractor struct {
+	staticKeys     []jwk
+	jwksFile       string
+	refresh        time.Duration
+	issuers        []string
+	audiences      []string
+	leeway         time.Duration
+	optionalExpiry bool
+	metadataKey    string
+	mu             sync.RWMutex
+	keys           []jwk
+	close          chan struct{}
+	done           chan struct{}
+	closeOnce      sync.Once
+}
+
+// jwk is a verification key
+type jwk struct {
+	kid string
+	// alg is the algorithm the key is restricted to, if any
+	alg string
+	key any
+}
+
+// NewJWTUserExtractor returns a new JWTUserExtractor. At least one static key or a JWKS file is required.
+// Close stops refreshing the JWKS file
+func NewJWTUserExtractor(opts ...JWTOpt) (*JWTUserExtractor, error) {
+	j := &JWTUserExtractor{
+		metadataKey: "authorization",
+		close:       make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	for _, k := range j.staticKeys {
+		switch key := k.key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		case []byte:
+			if len(key) < minHMACKeySize {
+				return nil, fmt.Errorf("authorizer: jwt hmac key %q is shorter than %d bytes", k.kid, minHMACKeySize)
+			}
+		default:
+			return nil, fmt.Errorf("authorizer: unsupported jwt key type %T", k.key)
+		}
+	}
+	j.keys = j.staticKeys
+	if j.jwksFile != "" {
+		if err := j.loadJWKS(); err != nil {
+			return nil, err
+		}
+	}
+	if len(j.keys) == 0 {
+		return nil, fmt.Errorf("authorizer: no jwt verification keys configured")
+	}
+	if j.jwksFile != "" && j.refresh > 0 {
+		go j.refreshJWKS()
+	} else {
+		close(j.done)
+	}
+	return j, nil
+}
+
+// Extract implements the UserExtractor function signature. It returns the verified claims of the bearer token in the
+// incoming metadata, or an Unauthenticated error if the token is missing or invalid
+func (j *JWTUserExtractor) Extract(ctx context.Context) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(j.metadataKey)
+	if len(values) == 0 {
+		return nil, status.Errorf(codes.Unauthenticated, "authorizer: missing %s metadata", j.metadataKey)
+	}
+	token := values[0]
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = token[7:]
+	}
+	claims, err := j.Verify(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return claims, nil
+}
+
+// Verify verifies the signature and claims of the token and returns its claims
+func (j *JWTUserExtractor) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("authorizer: malformed jwt")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("authorizer: malformed jwt header: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("authorizer: malformed jwt signature: %v", err)
+	}
+	if err := j.verifySignature(header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("authorizer: malformed jwt claims: %v", err)
+	}
+	if err := j.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Close stops refreshing the JWKS file
+func (j *JWTUserExtractor) Close() error {
+	j.closeOnce.Do(func() {
+		close(j.close)
+	})
+	<-j.done
+	return nil
+}
+
+func (j *JWTUserExtractor) verifySignature(alg, kid string, signed, signature []byte) error {
+	j.mu.RLock()
+	keys := j.keys
+	j.mu.RUnlock()
+	var matched bool
+	for _, k := range keys {
+		// tokens without a kid are verified with every key that supports their algorithm
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		matched = true
+		if err := verifyJWS(alg, k.key, signed, signature); err == nil {
+			return nil
+		}
+	}
+	if !matched {
+		return fmt.Errorf("authorizer: no jwt key found for kid %q and alg %q", kid, alg)
+	}
+	return fmt.Errorf("authorizer: invalid jwt signature")
+}
+
+func (j *JWTUserExtractor) verifyClaims(claims map[string]any) error {
+	now := time.Now()
+	exp, ok := claims["exp"]
+	if !ok && !j.optionalExpiry {
+		return fmt.Errorf("authorizer: jwt has no exp claim")
+	}
+	if ok {
+		t, ok := numericDate(exp)
+		if !ok {
+			return fmt.Errorf("authorizer: invalid jwt exp claim")
+		}
+		if !now.Before(t.Add(j.leeway)) {
+			return fmt.Errorf("authorizer: jwt is expired")
+		}
+	}
+	if nbf, ok := claims["nbf"]; ok {
+		t, ok := numericDate(nbf)
+		if !ok {
+			return fmt.Errorf("authorizer: invalid jwt nbf claim")
+		}
+		if now.Add(j.leeway).Before(t) {
+			return fmt.Errorf("authorizer: jwt is not valid yet")
+		}
+	}
+	if len(j.issuers) > 0 {
+		iss, _ := claims["iss"].(string)
+		if !contains(j.issuers, iss) {
+			return fmt.Errorf("authorizer: invalid jwt issuer %q", iss)
+		}
+	}
+	if len(j.audiences) > 0 {
+		var audiences []string
+		switch aud := claims["aud"].(type) {
+		case string:
+			audiences = []string{aud}
+		case []any:
+			for _, a := range aud {
+				if s, ok := a.(string); ok {
+					audiences = append(audiences, s)
+				}
+			}
+		}
+		var ok bool
+		for _, aud := range audiences {
+			if contains(j.audiences, aud) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("authorizer: invalid jwt audience")
+		}
+	}
+	return nil
+}
+
+func (j *JWTUserExtractor) refreshJWKS() {
+	defer close(j.done)
+	ticker := time.NewTicker(j.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.close:
+			return
+		case <-ticker.C:
+			if err := j.loadJWKS(); err != nil {
+				slog.Default().Error("authorizer: failed to refresh jwks", "error", err)
+			}
+		}
+	}
+}
+
+func (j *JWTUserExtractor) loadJWKS() error {
+	data, err := os.ReadFile(j.jwksFile)
+	if err != nil {
+		return fmt.Errorf("authorizer: failed to read jwks: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.keys = append(append([]jwk{}, j.staticKeys...), keys...)
+	j.mu.Unlock()
+	return nil
+}
+
+// parseJWKS parses the verification keys of a JSON Web Key Set (RFC 7517). Keys with "use": "enc" are ignored
+func parseJWKS(data []byte) ([]jwk, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("authorizer: failed to parse jwks: %w", err)
+	}
+	var keys []jwk
+	for i, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		key, err := parseJWK(k.Kty, k.Crv, k.N, k.E, k.X, k.Y, k.K)
+		if err != nil {
+			return nil, fmt.Errorf("authorizer: invalid jwks key %d (%s): %w", i, k.Kid, err)
+		}
+		keys = append(keys, jwk{kid: k.Kid, alg: k.Alg, key: key})
+	}
+	return keys, nil
+}
+
+func parseJWK(kty, crv, n, e, x, y, k string) (any, error) {
+	decode := func(s string) ([]byte, error) {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+	switch kty {
+	case "RSA":
+		nb, err := decode(n)
+		if err != nil {
+			return nil, err
+		}
+		eb, err := decode(e)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(new(big.Int).SetBytes(eb).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", crv)
+		}
+		xb, err := decode(x)
+		if err != nil {
+			return nil, err
+		}
+		yb, err := decode(y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(xb), Y: new(big.Int).SetBytes(yb)}, nil
+	case "OKP":
+		if crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", crv)
+		}
+		xb, err := decode(x)
+		if err != nil {
+			return nil, err
+		}
+		if len(xb) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(xb), nil
+	case "oct":
+		kb, err := decode(k)
+		if err != nil {
+			return nil, err
+		}
+		if len(kb) < minHMACKeySize {
+			return nil, fmt.Errorf("hmac key is shorter than %d bytes", minHMACKeySize)
+		}
+		return kb, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", kty)
+	}
+}
+
+// minHMACKeySize is the minimum size of HMAC secrets, the output size of HS256 (RFC 7518 section 3.2)
+const minHMACKeySize = 32
+
+// verifyJWS verifies the signature of a JWS with the given algorithm. The algorithm must match the type of the key
+// so a public key can't be used as an HMAC secret
+func verifyJWS(alg string, key any, signed, signature []byte) error {
+	var hash crypto.Hash
+	switch {
+	case strings.HasSuffix(alg, "256"):
+		hash = crypto.SHA256
+	case strings.HasSuffix(alg, "384"):
+		hash = crypto.SHA384
+	case strings.HasSuffix(alg, "512"):
+		hash = crypto.SHA512
+	}
+	switch {
+	case strings.HasPrefix(alg, "HS") && hash != 0:
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("key type mismatch")
+		}
+		// the secret must be at least as large as the output of the hash
+		if len(secret) < hash.Size() {
+			return fmt.Errorf("hmac key is too short for %s", alg)
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	case (strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")) && hash != 0:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type mismatch")
+		}
+		h := hash.New()
+		h.Write(signed)
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(pub, hash, h.Sum(nil), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), signature)
+	case strings.HasPrefix(alg, "ES") && hash != 0:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type mismatch")
+		}
+		// the curve of the key must be the curve of the algorithm (RFC 7518 section 3.4)
+		if pub.Curve != esCurves[alg] {
+			return fmt.Errorf("key curve mismatch")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature")
+		}
+		h := hash.New()
+		h.Write(signed)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	case alg == "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type mismatch")
+		}
+		if !ed25519.Verify(pub, signed, signature) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// esCurves are the curves of the ECDSA algorithms
+var esCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts a JWT NumericDate claim (seconds since the epoch) to a time
+func numericDate(v any) (time.Time, bool) {
+	seconds, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package authorizer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// signJWT signs the claims with the given algorithm and key
+func signJWT(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(v any) string {
+		bits, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode jwt: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(bits)
+	}
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	var (
+		signature []byte
+		err       error
+	)
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case "HS512":
+		mac := hmac.New(sha512.New, key.([]byte))
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+	case "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		ecKey := key.(*ecdsa.PrivateKey)
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		}
+	case "EdDSA":
+		signature = ed25519.Sign(key.(ed25519.PrivateKey), []byte(signed))
+	}
+	if err != nil {
+		t.Fatalf("failed to sign jwt: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJWKS(t *testing.T, path string, kid string, key *rsa.PublicKey) {
+	t.Helper()
+	jwks := map[string]any{
+		"keys": []map[string]any{
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	}
+	bits, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("failed to encode jwks: %v", err)
+	}
+	if err := os.WriteFile(path, bits, 0o600); err != nil {
+		t.Fatalf("failed to write jwks: %v", err)
+	}
+}
+
+func TestJWTUserExtractor(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksFile, "rsa", &rsaKey.PublicKey)
+
+	extractor, err := NewJWTUserExtractor(
+		WithJWKSFile(jwksFile, 10*time.Millisecond),
+		WithJWTKey("ec", &ecKey.PublicKey),
+		WithJWTKey("p384", &p384Key.PublicKey),
+		WithJWTKey("ed", edPub),
+		WithJWTKey("hmac", secret),
+		WithJWTIssuer("https://issuer.example.com"),
+		WithJWTAudience("example"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create jwt user extractor: %v", err)
+	}
+	defer extractor.Close()
+
+	now := time.Now()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": "123",
+			"iss": "https://issuer.example.com",
+			"aud": []string{"other", "example"},
+			"exp": now.Add(time.Hour).Unix(),
+			"nbf": now.Add(-time.Minute).Unix(),
+		}
+		for k, v := range overrides {
+			// nil overrides remove the claim
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+	type test struct {
+		name        string
+		token       string
+		expectError bool
+	}
+	tests := []test{
+		{name: "RS256 jwks key", token: signJWT(t, "RS256", "rsa", rsaKey, claims(nil))},
+		{name: "PS256 jwks key", token: signJWT(t, "PS256", "rsa", rsaKey, claims(nil))},
+		{name: "ES256 static key", token: signJWT(t, "ES256", "ec", ecKey, claims(nil))},
+		{name: "EdDSA static key", token: signJWT(t, "EdDSA", "ed", edKey, claims(nil))},
+		{name: "HS256 static key without kid", token: signJWT(t, "HS256", "", secret, claims(map[string]any{"aud": "example"}))},
+		{name: "expired", token: signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]any{"exp": now.Add(-time.Minute).Unix()})), expectError: true},
+		{name: "not valid yet", token: signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})), expectError: true},
+		{name: "invalid issuer", token: signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]any{"iss": "https://evil.example.com"})), expectError: true},
+		{name: "invalid audience", token: signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]any{"aud": "other"})), expectError: true},
+		{name: "unknown kid", token: signJWT(t, "RS256", "unknown", rsaKey, claims(nil)), expectError: true},
+		{name: "wrong key", token: signJWT(t, "HS256", "hmac", []byte("wrong"), claims(nil)), expectError: true},
+		{name: "key shorter than the hash", token: signJWT(t, "HS512", "hmac", secret, claims(nil)), expectError: true},
+		{name: "algorithm confusion", token: signJWT(t, "HS256", "rsa", rsaKey.PublicKey.N.Bytes(), claims(nil)), expectError: true},
+		{name: "none algorithm", token: signJWT(t, "none", "", nil, claims(nil)), expectError: true},
+		{name: "none algorithm with kid", token: signJWT(t, "none", "hmac", nil, claims(nil)), expectError: true},
+		{name: "wrong curve", token: signJWT(t, "ES256", "p384", p384Key, claims(nil)), expectError: true},
+		{name: "missing exp", token: signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]any{"exp": nil})), expectError: true},
+		{name: "malformed", token: "not.a.jwt", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+tt.token))
+			user, err := extractor.Extract(ctx)
+			if tt.expectError {
+				if status.Code(err) != codes.Unauthenticated {
+					t.Fatalf("expected error code %v, got %v", codes.Unauthenticated, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if user.(map[string]any)["sub"] != "123" {
+				t.Fatalf("expected sub claim, got %v", user)
+			}
+		})
+	}
+	if _, err := extractor.Extract(context.Background()); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected error code %v for missing token, got %v", codes.Unauthenticated, err)
+	}
+
+	// tokens without an exp claim are accepted if the expiry is optional
+	optional, err := NewJWTUserExtractor(WithJWTKey("hmac", secret), WithJWTOptionalExpiry())
+	if err != nil {
+		t.Fatalf("failed to create jwt user extractor: %v", err)
+	}
+	defer optional.Close()
+	if _, err := optional.Verify(signJWT(t, "HS256", "hmac", secret, claims(map[string]any{"exp": nil}))); err != nil {
+		t.Fatalf("expected token without exp to be accepted: %v", err)
+	}
+	if _, err := optional.Verify(signJWT(t, "HS256", "hmac", secret, claims(map[string]any{"exp": now.Add(-time.Minute).Unix()}))); err == nil {
+		t.Fatalf("expected expired token to be rejected")
+	}
+
+	// rotated jwks keys are picked up when the file is refreshed
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeJWKS(t, jwksFile, "rotated", &rotated.PublicKey)
+	token := signJWT(t, "RS256", "rotated", rotated, claims(nil))
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := extractor.Verify(token); err == nil {
+			break
+		} else if time.Since(start) > 5*time.Second {
+			t.Fatalf("expected rotated key to be loaded: %v", err)
+		}
+	}
+}
+
+func TestNewJWTUserExtractor_HMACKeys(t *testing.T) {
+	jwks := func(key map[string]any) string {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		data, err := json.Marshal(map[string]any{"keys": []map[string]any{key}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	secret := base64.RawURLEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	type test struct {
+		name      string
+		opt       JWTOpt
+		expectErr bool
+	}
+	tests := []test{
+		{name: "static key", opt: WithJWTKey("hmac", []byte("0123456789abcdef0123456789abcdef"))},
+		{name: "empty static key", opt: WithJWTKey("hmac", []byte{}), expectErr: true},
+		{name: "short static key", opt: WithJWTKey("hmac", []byte("secret")), expectErr: true},
+		{name: "jwks key", opt: WithJWKSFile(jwks(map[string]any{"kty": "oct", "kid": "hmac", "k": secret}), 0)},
+		{name: "jwks key without k", opt: WithJWKSFile(jwks(map[string]any{"kty": "oct", "kid": "hmac"}), 0), expectErr: true},
+		{name: "short jwks key", opt: WithJWKSFile(jwks(map[string]any{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}), 0), expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor, err := NewJWTUserExtractor(tt.opt)
+			if tt.expectErr {
+				if err == nil {
+					extractor.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			extractor.Close()
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package authorizer
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome is the outcome of an authorization as it is recorded by Metrics
+type Outcome string
+
+const (
+	// OutcomeAllow means the request was authorized
+	OutcomeAllow Outcome = "allow"
+	// OutcomeDeny means the request was denied
+	OutcomeDeny Outcome = "deny"
+	// OutcomeError means the authorizer returned an error
+	OutcomeError Outcome = "error"
+	// OutcomeUnauthenticated means the user extractor returned an error
+	OutcomeUnauthenticated Outcome = "unauthenticated"
+)
+
+// NoRule is the rule label of outcomes that weren't determined by a rule
+const NoRule = "none"
+
+// Metrics records the outcomes and evaluation latencies of the authorizations made by the interceptors
+type Metrics interface {
+	// IncDecision increments the number of authorizations of the method with the given outcome. rule is the name (or the
+	// index, if it has no name) of the rule that determined the decision, or NoRule
+	IncDecision(method string, outcome Outcome, rule string)
+	// ObserveEvaluation records the time it took the authorizer to evaluate a request to the method
+	ObserveEvaluation(method string, latency time.Duration)
+}
+
+// WithMetrics sets the metrics that record the outcome and evaluation latency of every authorization
+func WithMetrics(metrics Metrics) Opt {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// observe records the outcome of an authorization with the interceptor's metrics
+func (o *options) observe(method string, decision *Decision, latency time.Duration, err error) {
+	if o.metrics == nil {
+		return
+	}
+	o.metrics.ObserveEvaluation(method, latency)
+	switch {
+	case err != nil:
+		o.metrics.IncDecision(method, OutcomeError, NoRule)
+	case decision.RuleIndex < 0:
+		o.metrics.IncDecision(method, outcomeOf(decision), NoRule)
+	case decision.RuleName != "":
+		o.metrics.IncDecision(method, outcomeOf(decision), decision.RuleName)
+	default:
+		o.metrics.IncDecision(method, outcomeOf(decision), strconv.Itoa(decision.RuleIndex))
+	}
+}
+
+func outcomeOf(decision *Decision) Outcome {
+	if decision.Allowed() {
+		return OutcomeAllow
+	}
+	return OutcomeDeny
+}
+
+// DefaultLatencyBuckets are the upper bounds (in seconds) of the evaluation latency histogram buckets of PrometheusMetrics
+var DefaultLatencyBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1}
+
+// PrometheusMetrics is a Metrics implementation that is exposed in the Prometheus text exposition format by its
+// http.Handler implementation (ex: http.Handle("/metrics", metrics)). It exposes the metrics:
+//
+//	authorizer_decisions_total{method, decision, rule} counter
+//	authorizer_evaluation_seconds{method} histogram
+type PrometheusMetrics struct {
+	buckets   []float64
+	mu        sync.Mutex
+	decisions map[decisionLabels]uint64
+	latencies map[string]*histogram
+}
+
+type decisionLabels struct {
+	method  string
+	outcome Outcome
+	rule    string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewPrometheusMetrics returns a new PrometheusMetrics with the given latency histogram buckets (in seconds).
+// DefaultLatencyBuckets are used if no buckets are given
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetrics{
+		buckets:   buckets,
+		decisions: map[decisionLabels]uint64{},
+		latencies: map[string]*histogram{},
+	}
+}
+
+// IncDecision implements the Metrics interface
+func (p *PrometheusMetrics) IncDecision(method string, outcome Outcome, rule string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.decisions[decisionLabels{method: method, outcome: outcome, rule: rule}]++
+}
+
+// ObserveEvaluation implements the Metrics interface
+func (p *PrometheusMetrics) ObserveEvaluation(method string, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, ok := p.latencies[method]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		p.latencies[method] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(p.String()))
+}
+
+// String returns the metrics in the Prometheus text exposition format
+func (p *PrometheusMetrics) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var b strings.Builder
+	b.WriteString("# HELP authorizer_decisions_total The number of authorizations by method, decision and rule.\n")
+	b.WriteString("# TYPE authorizer_decisions_total counter\n")
+	labels := make([]decisionLabels, 0, len(p.decisions))
+	for l := range p.decisions {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		if labels[i].outcome != labels[j].outcome {
+			return labels[i].outcome < labels[j].outcome
+		}
+		return labels[i].rule < labels[j].rule
+	})
+	for _, l := range labels {
+		fmt.Fprintf(&b, "authorizer_decisions_total{method=%s,decision=%s,rule=%s} %d\n",
+			labelValue(l.method), labelValue(string(l.outcome)), labelValue(l.rule), p.decisions[l])
+	}
+	b.WriteString("# HELP authorizer_evaluation_seconds The time it took to evaluate the rules of a method.\n")
+	b.WriteString("# TYPE authorizer_evaluation_seconds histogram\n")
+	methods := make([]string, 0, len(p.latencies))
+	for method := range p.latencies {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		h := p.latencies[method]
+		for i, bound := range p.buckets {
+			fmt.Fprintf(&b, "authorizer_evaluation_seconds_bucket{method=%s,le=%s} %d\n",
+				labelValue(method), labelValue(strconv.FormatFloat(bound, 'g', -1, 64)), h.counts[i])
+		}
+		fmt.Fprintf(&b, "authorizer_evaluation_seconds_bucket{method=%s,le=\"+Inf\"} %d\n", labelValue(method), h.count)
+		fmt.Fprintf(&b, "authorizer_evaluation_seconds_sum{method=%s} %s\n", labelValue(method), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "authorizer_evaluation_seconds_count{method=%s} %d\n", labelValue(method), h.count)
+	}
+	return b.String()
+}
+
+// labelValue quotes a label value as it is written in the text exposition format
+func labelValue(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
@@ -0,0 +1,75 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	const (
+		allowed = "/example.ExampleService/Allowed"
+		denied  = "/example.ExampleService/Denied"
+	)
+	authz := &staticAuthorizer{rules: map[string]*authorize.RuleSet{
+		allowed: {Rules: []*authorize.Rule{{Expression: "true"}}},
+		denied:  {Rules: []*authorize.Rule{{Expression: "false"}}},
+	}}
+	metrics := NewPrometheusMetrics(0.5, 0.1)
+	var users []any
+	interceptor := UnaryServerInterceptor(authz, WithMetrics(metrics), WithUserExtractor(func(ctx context.Context) (any, error) {
+		if len(users) == 0 {
+			return nil, errors.New("no user")
+		}
+		user := users[0]
+		users = users[1:]
+		return user, nil
+	}))
+	handler := func(ctx context.Context, req any) (any, error) {
+		return req, nil
+	}
+	users = []any{"a", "b", "c"}
+	for _, method := range []string{allowed, allowed, denied, denied} {
+		_, _ = interceptor(context.Background(), &authorize.Rule{}, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+
+	server := httptest.NewServer(metrics)
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	for _, expected := range []string{
+		"# TYPE authorizer_decisions_total counter\n",
+		`authorizer_decisions_total{method="/example.ExampleService/Allowed",decision="allow",rule="none"} 2` + "\n",
+		`authorizer_decisions_total{method="/example.ExampleService/Denied",decision="deny",rule="none"} 1` + "\n",
+		`authorizer_decisions_total{method="/example.ExampleService/Denied",decision="unauthenticated",rule="none"} 1` + "\n",
+		"# TYPE authorizer_evaluation_seconds histogram\n",
+		`authorizer_evaluation_seconds_bucket{method="/example.ExampleService/Allowed",le="0.1"} 2` + "\n",
+		`authorizer_evaluation_seconds_bucket{method="/example.ExampleService/Allowed",le="0.5"} 2` + "\n",
+		`authorizer_evaluation_seconds_bucket{method="/example.ExampleService/Allowed",le="+Inf"} 2` + "\n",
+		`authorizer_evaluation_seconds_count{method="/example.ExampleService/Denied"} 1` + "\n",
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Fatalf("expected metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+	if labelValue("a\"b\\c\nd") != `"a\"b\\c\nd"` {
+		t.Fatalf("unexpected label value escaping: %s", labelValue("a\"b\\c\nd"))
+	}
+}
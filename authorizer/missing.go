@@ -0,0 +1,78 @@
+package authorizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// MissingRulesPolicy determines how requests to methods that have no rules are authorized
+type MissingRulesPolicy string
+
+const (
+	// MissingRulesAllowIfServiceAnnotated allows requests to a method without rules if another method of the same service
+	// has rules, and denies them otherwise. This is the default policy
+	MissingRulesAllowIfServiceAnnotated MissingRulesPolicy = "allow_if_service_annotated"
+	// MissingRulesDeny denies requests to methods without rules
+	MissingRulesDeny MissingRulesPolicy = "deny"
+	// MissingRulesAllow allows requests to methods without rules
+	MissingRulesAllow MissingRulesPolicy = "allow"
+	// MissingRulesError returns ErrMissingRules for requests to methods without rules
+	MissingRulesError MissingRulesPolicy = "error"
+)
+
+// ErrMissingRules is returned by authorizers with the MissingRulesError policy when a method has no rules
+var ErrMissingRules = errors.New("authorizer: no rules configured for method")
+
+// ParseMissingRulesPolicy parses the name of a MissingRulesPolicy. An empty name is the default policy
+func ParseMissingRulesPolicy(name string) (MissingRulesPolicy, error) {
+	switch policy := MissingRulesPolicy(strings.ToLower(name)); policy {
+	case "":
+		return MissingRulesAllowIfServiceAnnotated, nil
+	case MissingRulesAllowIfServiceAnnotated, MissingRulesDeny, MissingRulesAllow, MissingRulesError:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("authorizer: unknown missing rules policy %q (expected one of %s, %s, %s, %s)", name,
+			MissingRulesAllowIfServiceAnnotated, MissingRulesDeny, MissingRulesAllow, MissingRulesError)
+	}
+}
+
+// DecideMissingRules sets the decision of a request to a method that isn't in the rules map according to the policy
+func DecideMissingRules(policy MissingRulesPolicy, rules map[string]*authorize.RuleSet, method string, decision *Decision) error {
+	switch policy {
+	case MissingRulesAllow:
+		decision.Effect = EffectAllow
+		return nil
+	case MissingRulesDeny:
+		decision.Effect = EffectDeny
+		decision.Reason = "no rules configured for method"
+		return nil
+	case MissingRulesError:
+		return fmt.Errorf("%w: %s", ErrMissingRules, method)
+	case "", MissingRulesAllowIfServiceAnnotated:
+		if service := serviceOf(method); service != "" {
+			for m := range rules {
+				if strings.HasPrefix(m, service) {
+					decision.Effect = EffectAllow
+					return nil
+				}
+			}
+		}
+		decision.Effect = EffectDeny
+		decision.Reason = "no rules configured for method or its service"
+		return nil
+	default:
+		return fmt.Errorf("authorizer: unknown missing rules policy %q", policy)
+	}
+}
+
+// serviceOf returns the service prefix of a grpc method full name (ex: /example.ExampleService/), or an empty string
+// if the method isn't a full method name
+func serviceOf(method string) string {
+	if i := strings.LastIndex(method, "/"); i > 0 && strings.HasPrefix(method, "/") {
+		return method[:i+1]
+	}
+	return ""
+}
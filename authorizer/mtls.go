@@ -0,0 +1,86 @@
+package authorizer
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// MTLSUserExtractor is a UserExtractor that extracts the user from the verified client certificate of a mutual TLS
+// connection (see CertificateUser). The server must verify client certificates (ex: tls.RequireAndVerifyClientCert) -
+// requests without a verified client certificate are rejected with an Unauthenticated error
+func MTLSUserExtractor(ctx context.Context) (any, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "authorizer: missing peer")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "authorizer: connection doesn't use tls")
+	}
+	if len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil, status.Errorf(codes.Unauthenticated, "authorizer: missing verified client certificate")
+	}
+	return CertificateUser(info.State.VerifiedChains[0][0]), nil
+}
+
+// CertificateUser returns the attributes of a client certificate as they are injected into expressions as the user:
+//
+//	{
+//	  "subject": "CN=billing,O=Example",
+//	  "common_name": "billing",
+//	  "dns_names": ["billing.prod.svc"],
+//	  "uris": ["spiffe://prod/ns/billing/sa/default"],
+//	  "email_addresses": [],
+//	  "ip_addresses": [],
+//	  "spiffe_id": "spiffe://prod/ns/billing/sa/default",
+//	  "issuer": "CN=Example CA,O=Example",
+//	  "serial": "1a2b3c",
+//	  "not_before": "2023-11-01T00:00:00Z",
+//	  "not_after": "2024-11-01T00:00:00Z"
+//	}
+//
+// spiffe_id is the first spiffe:// URI SAN, or empty if the certificate doesn't have one. serial is hex encoded
+// and not_before/not_after are RFC 3339 timestamps
+func CertificateUser(cert *x509.Certificate) map[string]any {
+	uris := make([]any, 0, len(cert.URIs))
+	spiffeID := ""
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+		if spiffeID == "" && strings.EqualFold(uri.Scheme, "spiffe") {
+			spiffeID = uri.String()
+		}
+	}
+	ips := make([]any, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	return map[string]any{
+		"subject":         cert.Subject.String(),
+		"common_name":     cert.Subject.CommonName,
+		"dns_names":       stringList(cert.DNSNames),
+		"uris":            uris,
+		"email_addresses": stringList(cert.EmailAddresses),
+		"ip_addresses":    ips,
+		"spiffe_id":       spiffeID,
+		"issuer":          cert.Issuer.String(),
+		"serial":          cert.SerialNumber.Text(16),
+		"not_before":      cert.NotBefore.UTC().Format(time.RFC3339),
+		"not_after":       cert.NotAfter.UTC().Format(time.RFC3339),
+	}
+}
+
+// stringList converts a string slice to a list that both expression engines can index
+func stringList(values []string) []any {
+	list := make([]any, 0, len(values))
+	for _, v := range values {
+		list = append(list, v)
+	}
+	return list
+}
@@ -0,0 +1,90 @@
+package authorizer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestMTLSUserExtractor(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spiffeID, _ := url.Parse("spiffe://prod/ns/billing/sa/default")
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(0x1a2b3c),
+		Subject:        pkix.Name{CommonName: "billing", Organization: []string{"Example"}},
+		DNSNames:       []string{"billing.prod.svc"},
+		URIs:           []*url.URL{{Scheme: "https", Host: "example.com"}, spiffeID},
+		EmailAddresses: []string{"billing@example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		NotBefore:      notAfter.AddDate(-1, 0, 0),
+		NotAfter:       notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 52342}
+	verified := credentials.TLSInfo{State: tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}}
+	unverified := credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr, AuthInfo: verified})
+	user, err := MTLSUserExtractor(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]any{
+		"subject":     "CN=billing,O=Example",
+		"common_name": "billing",
+		"spiffe_id":   "spiffe://prod/ns/billing/sa/default",
+		"issuer":      "CN=billing,O=Example",
+		"serial":      "1a2b3c",
+		"not_before":  "2029-01-01T00:00:00Z",
+		"not_after":   "2030-01-01T00:00:00Z",
+	}
+	for k, v := range expected {
+		if got := user.(map[string]any)[k]; got != v {
+			t.Fatalf("expected %s to be %v, got %v", k, v, got)
+		}
+	}
+	if uris := user.(map[string]any)["uris"].([]any); len(uris) != 2 || uris[0] != "https://example.com" {
+		t.Fatalf("unexpected uris: %v", uris)
+	}
+	if ips := user.(map[string]any)["ip_addresses"].([]any); len(ips) != 1 || ips[0] != "10.0.0.1" {
+		t.Fatalf("unexpected ip addresses: %v", ips)
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"missing peer":           context.Background(),
+		"insecure connection":    peer.NewContext(context.Background(), &peer.Peer{Addr: addr}),
+		"unverified certificate": peer.NewContext(context.Background(), &peer.Peer{Addr: addr, AuthInfo: unverified}),
+	} {
+		if _, err := MTLSUserExtractor(ctx); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("%s: expected error code %v, got %v", name, codes.Unauthenticated, err)
+		}
+	}
+}
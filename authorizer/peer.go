@@ -0,0 +1,83 @@
+package authorizer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Peer is the remote peer of a grpc request. It is injected into expressions as the "peer" variable (see Peer.Map)
+type Peer struct {
+	// Address is the address of the peer (ex: 127.0.0.1:52342). Client interceptors use the target of the client connection
+	Address string
+	// IP is the ip address of the peer without the port, or empty if the address isn't an ip address
+	IP string
+	// AuthType is the auth type of the connection's credentials (ex: tls), or empty if the connection is insecure
+	AuthType string
+	// TLS is the state of the connection if it uses TLS
+	TLS *tls.ConnectionState
+}
+
+// PeerFromContext returns the remote peer of an incoming grpc request, or nil if the context has no peer
+func PeerFromContext(ctx context.Context) *Peer {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+	info := NewPeer(p.Addr.String())
+	if p.AuthInfo != nil {
+		info.AuthType = p.AuthInfo.AuthType()
+		if t, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			info.TLS = &t.State
+		}
+	}
+	return info
+}
+
+// NewPeer returns a Peer with the given address
+func NewPeer(address string) *Peer {
+	p := &Peer{Address: address}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		p.IP = ip.String()
+	}
+	return p
+}
+
+// Map returns the peer as it is injected into expressions:
+//
+//	{
+//	  "address": "127.0.0.1:52342",
+//	  "ip": "127.0.0.1",
+//	  "auth_type": "tls",
+//	  "tls": {"version": "TLS 1.3", "cipher_suite": "TLS_AES_128_GCM_SHA256", "server_name": "", "negotiated_protocol": "h2", "verified": true}
+//	}
+//
+// tls is null if the connection doesn't use TLS. A nil peer is returned with empty values
+func (p *Peer) Map() map[string]any {
+	if p == nil {
+		p = &Peer{}
+	}
+	m := map[string]any{
+		"address":   p.Address,
+		"ip":        p.IP,
+		"auth_type": p.AuthType,
+		"tls":       nil,
+	}
+	if p.TLS != nil {
+		m["tls"] = map[string]any{
+			"version":             tls.VersionName(p.TLS.Version),
+			"cipher_suite":        tls.CipherSuiteName(p.TLS.CipherSuite),
+			"server_name":         p.TLS.ServerName,
+			"negotiated_protocol": p.TLS.NegotiatedProtocol,
+			"verified":            len(p.TLS.VerifiedChains) > 0,
+		}
+	}
+	return m
+}
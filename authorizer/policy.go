@@ -0,0 +1,139 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// Validator is implemented by authorizers that can check that all of their rule expressions compile
+type Validator interface {
+	// Validate returns an error describing every rule expression that fails to compile
+	Validate() error
+}
+
+// AuthorizerFactory creates an Authorizer from a map of method names to RuleSets (ex: a closure over cel.NewCelAuthorizer)
+type AuthorizerFactory func(rules map[string]*authorize.RuleSet) (Authorizer, error)
+
+// ParsePolicy parses a policy document that maps grpc method full names to RuleSets. The document can be YAML or JSON,
+// and each RuleSet is decoded with protojson, for example:
+//
+//	/example.ExampleService/RequestMatch:
+//	  algorithm: COMBINING_ALGORITHM_DENY_OVERRIDES
+//	  rules:
+//	    - expression: "user.is_suspended"
+//	      effect: EFFECT_DENY
+//	    - expression: "request.account_id in user.account_ids"
+func ParsePolicy(data []byte) (map[string]*authorize.RuleSet, error) {
+	// yaml is a superset of json, so both formats are decoded as yaml and converted to json for protojson
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("authorizer: failed to parse policy: %w", err)
+	}
+	rules := make(map[string]*authorize.RuleSet, len(doc))
+	for method, value := range doc {
+		if !isFullMethod(method) {
+			return nil, fmt.Errorf("authorizer: invalid policy method %q: expected /package.Service/Method", method)
+		}
+		bits, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("authorizer: invalid policy for method %s: %w", method, err)
+		}
+		ruleSet := &authorize.RuleSet{}
+		if err := protojson.Unmarshal(bits, ruleSet); err != nil {
+			return nil, fmt.Errorf("authorizer: invalid policy for method %s: %w", method, err)
+		}
+		if len(ruleSet.GetRules()) == 0 {
+			return nil, fmt.Errorf("authorizer: invalid policy for method %s: no rules", method)
+		}
+		rules[method] = ruleSet
+	}
+	return rules, nil
+}
+
+// LoadPolicy reads and parses the YAML or JSON policy document at the given path (see ParsePolicy).
+// If the path is a directory, the policies of all of its .yaml, .yml and .json files are merged
+// and a method may only be declared in one of them
+func LoadPolicy(path string) (map[string]*authorize.RuleSet, error) {
+	files, err := policyFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := map[string]*authorize.RuleSet{}
+	declared := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("authorizer: failed to read policy: %w", err)
+		}
+		policy, err := ParsePolicy(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s)", err, file)
+		}
+		for method, ruleSet := range policy {
+			if other, ok := declared[method]; ok {
+				return nil, fmt.Errorf("authorizer: method %s is declared in both %s and %s", method, other, file)
+			}
+			declared[method] = file
+			rules[method] = ruleSet
+		}
+	}
+	return rules, nil
+}
+
+// policyFiles returns the policy files at the given path in lexical order
+func policyFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("authorizer: failed to read policy: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("authorizer: failed to read policy directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// LoadAuthorizer loads the policy document at the given path and creates an Authorizer from it with the factory.
+// If the Authorizer implements Validator, the policy is only accepted if all of its expressions compile
+func LoadAuthorizer(path string, factory AuthorizerFactory) (Authorizer, error) {
+	rules, err := LoadPolicy(path)
+	if err != nil {
+		return nil, err
+	}
+	authz, err := factory(rules)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := authz.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return authz, nil
+}
+
+// isFullMethod returns true if the method is a grpc method full name (ex: /example.ExampleService/RequestMatch)
+func isFullMethod(method string) bool {
+	parts := strings.Split(method, "/")
+	return len(parts) == 3 && parts[0] == "" && parts[1] != "" && parts[2] != ""
+}
@@ -0,0 +1,89 @@
+package authorizer
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+func TestParsePolicy(t *testing.T) {
+	type test struct {
+		name        string
+		policy      string
+		expectError bool
+		expect      map[string]*authorize.RuleSet
+	}
+	tests := []test{
+		{
+			name: "yaml",
+			policy: `
+/example.ExampleService/RequestMatch:
+  algorithm: COMBINING_ALGORITHM_DENY_OVERRIDES
+  authorizeStreamMessages: true
+  rules:
+    - expression: "user.is_suspended"
+      effect: EFFECT_DENY
+    - expression: "*"
+`,
+			expect: map[string]*authorize.RuleSet{
+				"/example.ExampleService/RequestMatch": {
+					Algorithm:               authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+					AuthorizeStreamMessages: true,
+					Rules: []*authorize.Rule{
+						{Expression: "user.is_suspended", Effect: authorize.Effect_EFFECT_DENY},
+						{Expression: "*"},
+					},
+				},
+			},
+		},
+		{
+			name:   "json",
+			policy: `{"/example.ExampleService/RequestMatch": {"rules": [{"expression": "true"}], "dry_run": true}}`,
+			expect: map[string]*authorize.RuleSet{
+				"/example.ExampleService/RequestMatch": {
+					DryRun: true,
+					Rules:  []*authorize.Rule{{Expression: "true"}},
+				},
+			},
+		},
+		{
+			name:        "invalid method",
+			policy:      `example.ExampleService.RequestMatch: {rules: [{expression: "true"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "unknown field",
+			policy:      `/example.ExampleService/RequestMatch: {rules: [{expr: "true"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "no rules",
+			policy:      `/example.ExampleService/RequestMatch: {algorithm: COMBINING_ALGORITHM_FIRST_APPLICABLE}`,
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParsePolicy([]byte(tt.policy))
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rules) != len(tt.expect) {
+				t.Fatalf("expected %d rule sets, got %d", len(tt.expect), len(rules))
+			}
+			for method, expect := range tt.expect {
+				if !proto.Equal(rules[method], expect) {
+					t.Fatalf("expected %v, got %v", expect, rules[method])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package authorizer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// ReloadOpt is an option for configuring a ReloadingAuthorizer
+type ReloadOpt func(r *ReloadingAuthorizer)
+
+// WithReloadInterval sets how often the policy is checked for changes. Defaults to 5 seconds
+func WithReloadInterval(interval time.Duration) ReloadOpt {
+	return func(r *ReloadingAuthorizer) {
+		r.interval = interval
+	}
+}
+
+// WithReloadErrorHandler sets the function that is called when a changed policy fails to load.
+// The last good policy keeps being enforced. By default, errors are logged with slog.Default()
+func WithReloadErrorHandler(handler func(err error)) ReloadOpt {
+	return func(r *ReloadingAuthorizer) {
+		r.errorHandler = handler
+	}
+}
+
+// ReloadingAuthorizer is an Authorizer that watches a policy file or directory (see LoadPolicy) and atomically
+// replaces its authorizer whenever the policy changes, so rules can be updated without restarting the server.
+// A changed policy is only applied if the factory succeeds and all of its expressions compile - otherwise,
+// the last good policy keeps being enforced and the error is reported to the error handler
+type ReloadingAuthorizer struct {
+	path         string
+	factory      AuthorizerFactory
+	interval     time.Duration
+	errorHandler func(err error)
+	current      atomic.Pointer[loadedAuthorizer]
+	version      uint64
+	fingerprint  string
+	mu           sync.Mutex
+	closeOnce    sync.Once
+	close        chan struct{}
+	done         chan struct{}
+}
+
+// loadedAuthorizer is an authorizer created from a version of the policy
+type loadedAuthorizer struct {
+	authorizer Authorizer
+	decider    DecisionAuthorizer
+	version    uint64
+}
+
+// NewReloadingAuthorizer loads the policy at the given path with the factory and starts watching it for changes.
+// It returns an error if the initial policy fails to load. Close stops watching the policy
+func NewReloadingAuthorizer(path string, factory AuthorizerFactory, opts ...ReloadOpt) (*ReloadingAuthorizer, error) {
+	r := &ReloadingAuthorizer{
+		path:     path,
+		factory:  factory,
+		interval: 5 * time.Second,
+		errorHandler: func(err error) {
+			slog.Default().Error("authorizer: failed to reload policy", "error", err)
+		},
+		close: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	go r.watch()
+	return r, nil
+}
+
+// Reload loads the policy and replaces the current authorizer if it is valid
+func (r *ReloadingAuthorizer) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the fingerprint is taken before loading so changes made while loading are picked up by the next check
+	fingerprint, err := policyFingerprint(r.path)
+	if err != nil {
+		return err
+	}
+	r.fingerprint = fingerprint
+	authz, err := LoadAuthorizer(r.path, r.factory)
+	if err != nil {
+		return err
+	}
+	r.version++
+	r.current.Store(&loadedAuthorizer{
+		authorizer: authz,
+		decider:    AsDecisionAuthorizer(authz),
+		version:    r.version,
+	})
+	return nil
+}
+
+// Close stops watching the policy. The last loaded policy keeps being enforced
+func (r *ReloadingAuthorizer) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.close)
+	})
+	<-r.done
+	return nil
+}
+
+// AuthorizeMethod implements the Authorizer interface with the current policy
+func (r *ReloadingAuthorizer) AuthorizeMethod(ctx context.Context, method string, params *RuleExecutionParams) (bool, error) {
+	return r.current.Load().authorizer.AuthorizeMethod(ctx, method, params)
+}
+
+// Decide implements the DecisionAuthorizer interface with the current policy
+func (r *ReloadingAuthorizer) Decide(ctx context.Context, method string, params *RuleExecutionParams) (*Decision, error) {
+	return r.current.Load().decider.Decide(ctx, method, params)
+}
+
+// RuleSet implements the RuleSetLookup interface with the current policy
+func (r *ReloadingAuthorizer) RuleSet(method string) (*authorize.RuleSet, bool) {
+	rules := lookupRuleSet(r.current.Load().authorizer, method)
+	return rules, rules != nil
+}
+
+// PolicyVersion implements the VersionedPolicy interface. The version is incremented every time a policy is loaded
+func (r *ReloadingAuthorizer) PolicyVersion() uint64 {
+	return r.current.Load().version
+}
+
+func (r *ReloadingAuthorizer) watch() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.close:
+			return
+		case <-ticker.C:
+			if err := r.reloadIfChanged(); err != nil && r.errorHandler != nil {
+				r.errorHandler(err)
+			}
+		}
+	}
+}
+
+func (r *ReloadingAuthorizer) reloadIfChanged() error {
+	fingerprint, err := policyFingerprint(r.path)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	changed := fingerprint != r.fingerprint
+	r.mu.Unlock()
+	if !changed {
+		return nil
+	}
+	return r.Reload()
+}
+
+// policyFingerprint returns a fingerprint of the names, sizes and modification times of the policy files at the given path
+func policyFingerprint(path string) (string, error) {
+	files, err := policyFiles(path)
+	if err != nil {
+		return "", err
+	}
+	var fingerprint strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", fmt.Errorf("authorizer: failed to read policy: %w", err)
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String(), nil
+}
@@ -0,0 +1,147 @@
+package authorizer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// staticAuthorizer is a minimal engine that only understands the "true" and "false" expressions
+type staticAuthorizer struct {
+	rules map[string]*authorize.RuleSet
+}
+
+func (s *staticAuthorizer) AuthorizeMethod(ctx context.Context, method string, params *RuleExecutionParams) (bool, error) {
+	rules, ok := s.rules[method]
+	return ok && rules.GetRules()[0].GetExpression() == "true", nil
+}
+
+func (s *staticAuthorizer) RuleSet(method string) (*authorize.RuleSet, bool) {
+	rules, ok := s.rules[method]
+	return rules, ok
+}
+
+func (s *staticAuthorizer) Validate() error {
+	for method, rules := range s.rules {
+		for _, rule := range rules.GetRules() {
+			if rule.GetExpression() != "true" && rule.GetExpression() != "false" {
+				return fmt.Errorf("%s: invalid expression %q", method, rule.GetExpression())
+			}
+		}
+	}
+	return nil
+}
+
+func TestReloadingAuthorizer(t *testing.T) {
+	const method = "/example.ExampleService/RequestMatch"
+	dir := t.TempDir()
+	writePolicy := func(expression string) {
+		t.Helper()
+		policy := fmt.Sprintf(`%s: {rules: [{expression: "%s"}]}`, method, expression)
+		if err := os.WriteFile(filepath.Join(dir, "policy.yaml"), []byte(policy), 0o600); err != nil {
+			t.Fatalf("failed to write policy: %v", err)
+		}
+	}
+	writePolicy("true")
+	errs := make(chan error, 10)
+	authz, err := NewReloadingAuthorizer(dir, func(rules map[string]*authorize.RuleSet) (Authorizer, error) {
+		return &staticAuthorizer{rules: rules}, nil
+	}, WithReloadInterval(10*time.Millisecond), WithReloadErrorHandler(func(err error) {
+		errs <- err
+	}))
+	if err != nil {
+		t.Fatalf("failed to create reloading authorizer: %v", err)
+	}
+	defer authz.Close()
+	expectAllow := func(expect bool) {
+		t.Helper()
+		var allowed bool
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+			allowed, err = authz.AuthorizeMethod(context.Background(), method, &RuleExecutionParams{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if allowed == expect {
+				return
+			}
+		}
+		t.Fatalf("expected allow=%v, got %v", expect, allowed)
+	}
+	expectAllow(true)
+	if _, ok := authz.RuleSet(method); !ok {
+		t.Fatalf("expected rule set for %s", method)
+	}
+	writePolicy("false")
+	expectAllow(false)
+	// the last good policy is kept when the changed policy is invalid
+	writePolicy("invalid")
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatalf("expected reload error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected reload error")
+	}
+	expectAllow(false)
+	if _, err := NewReloadingAuthorizer(filepath.Join(dir, "missing.yaml"), func(rules map[string]*authorize.RuleSet) (Authorizer, error) {
+		return &staticAuthorizer{rules: rules}, nil
+	}); err == nil {
+		t.Fatalf("expected error loading missing policy")
+	}
+}
+
+func TestReloadingAuthorizer_Cache(t *testing.T) {
+	const method = "/example.ExampleService/RequestMatch"
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	writePolicy := func(expression string) {
+		t.Helper()
+		policy := fmt.Sprintf(`%s: {rules: [{expression: "%s"}], cache: {metadataKeys: ["x-account-id"]}}`, method, expression)
+		if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+			t.Fatalf("failed to write policy: %v", err)
+		}
+	}
+	writePolicy("true")
+	authz, err := NewReloadingAuthorizer(path, func(rules map[string]*authorize.RuleSet) (Authorizer, error) {
+		return &staticAuthorizer{rules: rules}, nil
+	}, WithReloadInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create reloading authorizer: %v", err)
+	}
+	defer authz.Close()
+	cache := NewCachingAuthorizer(authz)
+	decide := func() *Decision {
+		t.Helper()
+		decision, err := cache.Decide(context.Background(), method, &RuleExecutionParams{User: cacheUser{id: "1"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return decision
+	}
+	if decision := decide(); !decision.Allowed() || decision.Cached {
+		t.Fatalf("expected evaluated allow decision, got %+v", decision)
+	}
+	if decision := decide(); !decision.Allowed() || !decision.Cached {
+		t.Fatalf("expected cached allow decision, got %+v", decision)
+	}
+	version := authz.PolicyVersion()
+	// decisions of the previous policy are purged when the policy is reloaded
+	writePolicy("false")
+	if err := authz.Reload(); err != nil {
+		t.Fatalf("failed to reload policy: %v", err)
+	}
+	if authz.PolicyVersion() == version {
+		t.Fatalf("expected the policy version to change")
+	}
+	if decision := decide(); decision.Allowed() || decision.Cached {
+		t.Fatalf("expected evaluated deny decision, got %+v", decision)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("expected only the decision of the current policy to be cached, got %d", cache.Len())
+	}
+}
@@ -0,0 +1,89 @@
+package authorizer
+
+import (
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// WildcardExpression is a rule expression that always evaluates to true without being compiled by an expression engine
+const WildcardExpression = "*"
+
+// RuleEvaluator evaluates the rule at the given index of a RuleSet and returns true if its expression evaluated to true
+type RuleEvaluator func(index int) (bool, error)
+
+// RuleEffect returns the effect of the rule when its expression evaluates to true. Unspecified effects are treated as allow
+func RuleEffect(rule *authorize.Rule) Effect {
+	if rule.GetEffect() == authorize.Effect_EFFECT_DENY {
+		return EffectDeny
+	}
+	return EffectAllow
+}
+
+// EvaluateRuleSet evaluates the rules of the RuleSet with the given evaluator and combines their results into the decision
+// using the RuleSet's combining algorithm. If no rules evaluate to true, the request is denied.
+// Rules with the WildcardExpression always evaluate to true and are not passed to the evaluator.
+func EvaluateRuleSet(rules *authorize.RuleSet, decision *Decision, eval RuleEvaluator) error {
+	var (
+		allowIndex = -1
+		denyIndex  = -1
+	)
+	for i, rule := range rules.GetRules() {
+		decision.RulesEvaluated++
+		if rule.GetExpression() != WildcardExpression {
+			pass, err := eval(i)
+			if err != nil {
+				return err
+			}
+			if !pass {
+				continue
+			}
+		}
+		effect := RuleEffect(rule)
+		switch rules.GetAlgorithm() {
+		case authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES:
+			if effect == EffectDeny {
+				setDecisionRule(decision, rules, i)
+				return nil
+			}
+			if allowIndex == -1 {
+				allowIndex = i
+			}
+		case authorize.CombiningAlgorithm_COMBINING_ALGORITHM_PERMIT_OVERRIDES:
+			if effect == EffectAllow {
+				setDecisionRule(decision, rules, i)
+				return nil
+			}
+			if denyIndex == -1 {
+				denyIndex = i
+			}
+		default:
+			setDecisionRule(decision, rules, i)
+			return nil
+		}
+	}
+	switch {
+	case allowIndex != -1:
+		setDecisionRule(decision, rules, allowIndex)
+	case denyIndex != -1:
+		setDecisionRule(decision, rules, denyIndex)
+	default:
+		decision.Effect = EffectDeny
+		decision.Reason = "no rule evaluated to true"
+		decision.Denial = rules.GetDenial()
+	}
+	return nil
+}
+
+func setDecisionRule(decision *Decision, rules *authorize.RuleSet, index int) {
+	rule := rules.GetRules()[index]
+	decision.Effect = RuleEffect(rule)
+	decision.RuleIndex = index
+	decision.RuleName = rule.GetName()
+	decision.Expression = rule.GetExpression()
+	if decision.Effect == EffectDeny {
+		decision.Reason = "deny rule evaluated to true"
+		decision.Denial = rule.GetDenial()
+		if decision.Denial == nil {
+			decision.Denial = rules.GetDenial()
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package authorizer
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// TracerName is the name of the tracer that creates authorization spans
+const TracerName = "github.com/autom8ter/protoc-gen-authorize/authorizer"
+
+// span attribute keys of authorization spans
+const (
+	// AttributeMethod is the grpc method being authorized
+	AttributeMethod = attribute.Key("authorizer.method")
+	// AttributeDecision is the effect of the decision (allow or deny)
+	AttributeDecision = attribute.Key("authorizer.decision")
+	// AttributeEngine is the expression engine that made the decision
+	AttributeEngine = attribute.Key("authorizer.engine")
+	// AttributeRuleIndex is the index of the rule that determined the decision, or of the evaluated rule
+	AttributeRuleIndex = attribute.Key("authorizer.rule.index")
+	// AttributeRuleName is the name of the rule that determined the decision, or of the evaluated rule, if it has one
+	AttributeRuleName = attribute.Key("authorizer.rule.name")
+	// AttributeRuleExpression is the expression of the rule that determined the decision, or of the evaluated rule
+	AttributeRuleExpression = attribute.Key("authorizer.rule.expression")
+	// AttributeRuleResult is the result of the evaluated rule's expression
+	AttributeRuleResult = attribute.Key("authorizer.rule.result")
+	// AttributeCached is true if the decision was served from a CachingAuthorizer
+	AttributeCached = attribute.Key("authorizer.cached")
+	// AttributeDryRun is true if the decision was not enforced (see WithDryRun)
+	AttributeDryRun = attribute.Key("authorizer.dry_run")
+)
+
+// WithTracerProvider sets the tracer provider used to record a span for every authorization made by the interceptor.
+// Engines created with their own WithTracerProvider option record child spans for their decisions and rule evaluations
+func WithTracerProvider(provider trace.TracerProvider) Opt {
+	return func(o *options) {
+		o.tracer = provider.Tracer(TracerName)
+	}
+}
+
+// TraceDecision records the decision returned by decide as a span with the given name. The span is the parent of the
+// spans created by decide with the context it is passed. If the tracer is nil, decide is called as is
+func TraceDecision(ctx context.Context, tracer trace.Tracer, name, method string, params *RuleExecutionParams, decide DecideFunc) (*Decision, error) {
+	if tracer == nil {
+		return decide(ctx, method, params)
+	}
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(AttributeMethod.String(method)))
+	defer span.End()
+	decision, err := decide(ctx, method, params)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+		return decision, err
+	}
+	if decision != nil {
+		span.SetAttributes(
+			AttributeDecision.String(string(decision.Effect)),
+			AttributeRuleIndex.Int(decision.RuleIndex),
+			AttributeCached.Bool(decision.Cached),
+		)
+		if decision.Engine != "" {
+			span.SetAttributes(AttributeEngine.String(decision.Engine))
+		}
+		if decision.RuleName != "" {
+			span.SetAttributes(AttributeRuleName.String(decision.RuleName))
+		}
+		if decision.Expression != "" {
+			span.SetAttributes(AttributeRuleExpression.String(decision.Expression))
+		}
+	}
+	return decision, nil
+}
+
+// TraceRuleEvaluator returns a RuleEvaluator that records every rule evaluation of eval as a span with the given name.
+// If the tracer is nil, eval is returned as is
+func TraceRuleEvaluator(ctx context.Context, tracer trace.Tracer, name string, rules *authorize.RuleSet, eval RuleEvaluator) RuleEvaluator {
+	if tracer == nil {
+		return eval
+	}
+	return func(index int) (bool, error) {
+		rule := rules.GetRules()[index]
+		_, span := tracer.Start(ctx, name, trace.WithAttributes(
+			AttributeRuleIndex.Int(index),
+			AttributeRuleExpression.String(rule.GetExpression()),
+		))
+		if rule.GetName() != "" {
+			span.SetAttributes(AttributeRuleName.String(rule.GetName()))
+		}
+		defer span.End()
+		pass, err := eval(index)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+			return pass, err
+		}
+		span.SetAttributes(AttributeRuleResult.Bool(pass))
+		return pass, nil
+	}
+}
@@ -1,247 +0,0 @@
-// Code generated by protoc-gen-go. DO NOT EDIT.
-// versions:
-// 	protoc-gen-go v1.31.0
-// 	protoc        (unknown)
-// source: authorize/authorize.proto
-
-package authorize
-
-import (
-	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
-	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
-	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
-	reflect "reflect"
-	sync "sync"
-)
-
-const (
-	// Verify that this generated code is sufficiently up-to-date.
-	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
-	// Verify that runtime/protoimpl is sufficiently up-to-date.
-	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
-)
-
-type RuleSet struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	// The rules to apply to a request.
-	Rules []*Rule `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
-}
-
-func (x *RuleSet) Reset() {
-	*x = RuleSet{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_authorize_authorize_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
-}
-
-func (x *RuleSet) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*RuleSet) ProtoMessage() {}
-
-func (x *RuleSet) ProtoReflect() protoreflect.Message {
-	mi := &file_authorize_authorize_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use RuleSet.ProtoReflect.Descriptor instead.
-func (*RuleSet) Descriptor() ([]byte, []int) {
-	return file_authorize_authorize_proto_rawDescGZIP(), []int{0}
-}
-
-func (x *RuleSet) GetRules() []*Rule {
-	if x != nil {
-		return x.Rules
-	}
-	return nil
-}
-
-// Rule is a single rule that is used to authorize a request.
-type Rule struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	// The expression to evaluate. This is a string that is evaluated against
-	// the request. The expression must evaluate to a boolean value.
-	// If the expression evaluates to true, then the request is authorized.
-	Expression string `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
-}
-
-func (x *Rule) Reset() {
-	*x = Rule{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_authorize_authorize_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
-}
-
-func (x *Rule) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*Rule) ProtoMessage() {}
-
-func (x *Rule) ProtoReflect() protoreflect.Message {
-	mi := &file_authorize_authorize_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use Rule.ProtoReflect.Descriptor instead.
-func (*Rule) Descriptor() ([]byte, []int) {
-	return file_authorize_authorize_proto_rawDescGZIP(), []int{1}
-}
-
-func (x *Rule) GetExpression() string {
-	if x != nil {
-		return x.Expression
-	}
-	return ""
-}
-
-var file_authorize_authorize_proto_extTypes = []protoimpl.ExtensionInfo{
-	{
-		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
-		ExtensionType: (*RuleSet)(nil),
-		Field:         73902,
-		Name:          "authorize.rules",
-		Tag:           "bytes,73902,opt,name=rules",
-		Filename:      "authorize/authorize.proto",
-	},
-}
-
-// Extension fields to descriptorpb.MethodOptions.
-var (
-	// Rules to apply to requests to this method.
-	// If a single rule evaluates to true, then the request is authorized.
-	// If no rules evaluate to true, then the request is not authorized.
-	//
-	// optional authorize.RuleSet rules = 73902;
-	E_Rules = &file_authorize_authorize_proto_extTypes[0]
-)
-
-var File_authorize_authorize_proto protoreflect.FileDescriptor
-
-var file_authorize_authorize_proto_rawDesc = []byte{
-	0x0a, 0x19, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2f, 0x61, 0x75, 0x74, 0x68,
-	0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x61, 0x75, 0x74,
-	0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
-	0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x30, 0x0a, 0x07, 0x52, 0x75, 0x6c, 0x65,
-	0x53, 0x65, 0x74, 0x12, 0x25, 0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x52,
-	0x75, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x22, 0x26, 0x0a, 0x04, 0x52, 0x75,
-	0x6c, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69,
-	0x6f, 0x6e, 0x3a, 0x4a, 0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x12, 0x1e, 0x2e, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65,
-	0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xae, 0xc1, 0x04, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e,
-	0x52, 0x75, 0x6c, 0x65, 0x53, 0x65, 0x74, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x42, 0x34,
-	0x5a, 0x32, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x75, 0x74,
-	0x6f, 0x6d, 0x38, 0x74, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x65, 0x6e,
-	0x2f, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x3b, 0x61, 0x75, 0x74, 0x68, 0x6f,
-	0x72, 0x69, 0x7a, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
-}
-
-var (
-	file_authorize_authorize_proto_rawDescOnce sync.Once
-	file_authorize_authorize_proto_rawDescData = file_authorize_authorize_proto_rawDesc
-)
-
-func file_authorize_authorize_proto_rawDescGZIP() []byte {
-	file_authorize_authorize_proto_rawDescOnce.Do(func() {
-		file_authorize_authorize_proto_rawDescData = protoimpl.X.CompressGZIP(file_authorize_authorize_proto_rawDescData)
-	})
-	return file_authorize_authorize_proto_rawDescData
-}
-
-var file_authorize_authorize_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_authorize_authorize_proto_goTypes = []interface{}{
-	(*RuleSet)(nil),                    // 0: authorize.RuleSet
-	(*Rule)(nil),                       // 1: authorize.Rule
-	(*descriptorpb.MethodOptions)(nil), // 2: google.protobuf.MethodOptions
-}
-var file_authorize_authorize_proto_depIdxs = []int32{
-	1, // 0: authorize.RuleSet.rules:type_name -> authorize.Rule
-	2, // 1: authorize.rules:extendee -> google.protobuf.MethodOptions
-	0, // 2: authorize.rules:type_name -> authorize.RuleSet
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	2, // [2:3] is the sub-list for extension type_name
-	1, // [1:2] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
-}
-
-func init() { file_authorize_authorize_proto_init() }
-func file_authorize_authorize_proto_init() {
-	if File_authorize_authorize_proto != nil {
-		return
-	}
-	if !protoimpl.UnsafeEnabled {
-		file_authorize_authorize_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RuleSet); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_authorize_authorize_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Rule); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
-	type x struct{}
-	out := protoimpl.TypeBuilder{
-		File: protoimpl.DescBuilder{
-			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_authorize_authorize_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   2,
-			NumExtensions: 1,
-			NumServices:   0,
-		},
-		GoTypes:           file_authorize_authorize_proto_goTypes,
-		DependencyIndexes: file_authorize_authorize_proto_depIdxs,
-		MessageInfos:      file_authorize_authorize_proto_msgTypes,
-		ExtensionInfos:    file_authorize_authorize_proto_extTypes,
-	}.Build()
-	File_authorize_authorize_proto = out.File
-	file_authorize_authorize_proto_rawDesc = nil
-	file_authorize_authorize_proto_goTypes = nil
-	file_authorize_authorize_proto_depIdxs = nil
-}
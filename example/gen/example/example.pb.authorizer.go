@@ -1,16 +1,24 @@
 package example
 
 import (
-	"github.com/autom8ter/proto/gen/authorize"
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 
 	"github.com/autom8ter/protoc-gen-authorize/authorizer/javascript"
 )
 
 // NewAuthorizer returns a new javascript authorizer. The rules map is a map of method names to RuleSets. The RuleSets are used to
-// authorize the method. The RuleSets are evaluated in order and the first rule that evaluates to true will authorize
-// the request. The mapping can be generated with the protoc-gen-authorize plugin.
+// authorize the method. The rules of a RuleSet are combined using the RuleSet's combining algorithm - by default,
+// the effect of the first rule that evaluates to true is applied to the request.
+// The mapping can be generated with the protoc-gen-authorize plugin.
 func NewAuthorizer(opts ...javascript.Opt) (*javascript.JavascriptAuthorizer, error) {
 	return javascript.NewJavascriptAuthorizer(map[string]*authorize.RuleSet{
+		ExampleService_AllowAll_FullMethodName: {
+			Rules: []*authorize.Rule{
+				{
+					Expression: "*",
+				},
+			},
+		},
 		ExampleService_MetadataMatch_FullMethodName: {
 			Rules: []*authorize.Rule{
 				{
@@ -20,9 +28,22 @@ func NewAuthorizer(opts ...javascript.Opt) (*javascript.JavascriptAuthorizer, er
 					Expression: "user.IsSuperAdmin",
 				},
 			},
+			Cache: &authorize.DecisionCache{
+				MetadataKeys: []string{"x-account-id"},
+			},
 		},
 		ExampleService_RequestMatch_FullMethodName: {
 			Rules: []*authorize.Rule{
+				{
+					Expression: "user.IsSuspended",
+					Name:       "suspended-users",
+					Effect:     authorize.Effect_EFFECT_DENY,
+					Denial: &authorize.Denial{
+						Message: "account suspended",
+						Reason:  "ACCOUNT_SUSPENDED",
+						Domain:  "example.com",
+					},
+				},
 				{
 					Expression: "user.AccountIds.includes(request.AccountId) && user.Roles.includes('admin')",
 				},
@@ -30,6 +51,18 @@ func NewAuthorizer(opts ...javascript.Opt) (*javascript.JavascriptAuthorizer, er
 					Expression: "user.IsSuperAdmin",
 				},
 			},
+			Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+		},
+		ExampleService_StreamMatch_FullMethodName: {
+			Rules: []*authorize.Rule{
+				{
+					Expression: "request === null ? user.AccountIds.length > 0 : user.AccountIds.includes(request.AccountId)",
+				},
+				{
+					Expression: "user.IsSuperAdmin",
+				},
+			},
+			AuthorizeStreamMessages: true,
 		},
 	}, opts...)
 }
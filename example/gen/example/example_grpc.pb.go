@@ -22,6 +22,7 @@ const _ = grpc.SupportPackageIsVersion7
 const (
 	ExampleService_RequestMatch_FullMethodName  = "/authorize.ExampleService/RequestMatch"
 	ExampleService_MetadataMatch_FullMethodName = "/authorize.ExampleService/MetadataMatch"
+	ExampleService_StreamMatch_FullMethodName   = "/authorize.ExampleService/StreamMatch"
 	ExampleService_AllowAll_FullMethodName      = "/authorize.ExampleService/AllowAll"
 )
 
@@ -30,10 +31,15 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ExampleServiceClient interface {
 	// RequestMatch - Only super admins OR users with the admin role and access to the account id in the request will be allowed
+	// Suspended users are always denied (even super admins)
 	RequestMatch(ctx context.Context, in *Request, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// MetadataMatch - Only super admins OR users with the admin role and access to the account id in the metadata will be allowed
+	// Decisions are cached per user and account id (the rules only depend on the user and the x-account-id metadata)
 	MetadataMatch(ctx context.Context, in *Request, opts ...grpc.CallOption) (*emptypb.Empty, error)
-	// AllowAll is an example of how to configure a method to allow all requests
+	// StreamMatch - The user must have access to an account to open the stream (the request is null),
+	// then every message in the stream must match an account the user has access to
+	StreamMatch(ctx context.Context, opts ...grpc.CallOption) (ExampleService_StreamMatchClient, error)
+	// AllowAll is an example of how to configure a method to allow all requests (a single rule with a wildcard expression)
 	AllowAll(ctx context.Context, in *Request, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
@@ -63,6 +69,40 @@ func (c *exampleServiceClient) MetadataMatch(ctx context.Context, in *Request, o
 	return out, nil
 }
 
+func (c *exampleServiceClient) StreamMatch(ctx context.Context, opts ...grpc.CallOption) (ExampleService_StreamMatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ExampleService_ServiceDesc.Streams[0], ExampleService_StreamMatch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exampleServiceStreamMatchClient{stream}
+	return x, nil
+}
+
+type ExampleService_StreamMatchClient interface {
+	Send(*Request) error
+	CloseAndRecv() (*emptypb.Empty, error)
+	grpc.ClientStream
+}
+
+type exampleServiceStreamMatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *exampleServiceStreamMatchClient) Send(m *Request) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *exampleServiceStreamMatchClient) CloseAndRecv() (*emptypb.Empty, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(emptypb.Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *exampleServiceClient) AllowAll(ctx context.Context, in *Request, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, ExampleService_AllowAll_FullMethodName, in, out, opts...)
@@ -77,10 +117,15 @@ func (c *exampleServiceClient) AllowAll(ctx context.Context, in *Request, opts .
 // for forward compatibility
 type ExampleServiceServer interface {
 	// RequestMatch - Only super admins OR users with the admin role and access to the account id in the request will be allowed
+	// Suspended users are always denied (even super admins)
 	RequestMatch(context.Context, *Request) (*emptypb.Empty, error)
 	// MetadataMatch - Only super admins OR users with the admin role and access to the account id in the metadata will be allowed
+	// Decisions are cached per user and account id (the rules only depend on the user and the x-account-id metadata)
 	MetadataMatch(context.Context, *Request) (*emptypb.Empty, error)
-	// AllowAll is an example of how to configure a method to allow all requests
+	// StreamMatch - The user must have access to an account to open the stream (the request is null),
+	// then every message in the stream must match an account the user has access to
+	StreamMatch(ExampleService_StreamMatchServer) error
+	// AllowAll is an example of how to configure a method to allow all requests (a single rule with a wildcard expression)
 	AllowAll(context.Context, *Request) (*emptypb.Empty, error)
 	mustEmbedUnimplementedExampleServiceServer()
 }
@@ -95,6 +140,9 @@ func (UnimplementedExampleServiceServer) RequestMatch(context.Context, *Request)
 func (UnimplementedExampleServiceServer) MetadataMatch(context.Context, *Request) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MetadataMatch not implemented")
 }
+func (UnimplementedExampleServiceServer) StreamMatch(ExampleService_StreamMatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMatch not implemented")
+}
 func (UnimplementedExampleServiceServer) AllowAll(context.Context, *Request) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AllowAll not implemented")
 }
@@ -147,6 +195,32 @@ func _ExampleService_MetadataMatch_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ExampleService_StreamMatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ExampleServiceServer).StreamMatch(&exampleServiceStreamMatchServer{stream})
+}
+
+type ExampleService_StreamMatchServer interface {
+	SendAndClose(*emptypb.Empty) error
+	Recv() (*Request, error)
+	grpc.ServerStream
+}
+
+type exampleServiceStreamMatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *exampleServiceStreamMatchServer) SendAndClose(m *emptypb.Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *exampleServiceStreamMatchServer) Recv() (*Request, error) {
+	m := new(Request)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _ExampleService_AllowAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Request)
 	if err := dec(in); err != nil {
@@ -185,6 +259,12 @@ var ExampleService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ExampleService_AllowAll_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMatch",
+			Handler:       _ExampleService_StreamMatch_Handler,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "example/example.proto",
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -29,10 +30,12 @@ func userExtractor(ctx context.Context) (any, error) {
 
 func runServer() error {
 	// create a new authorizer from the generated function(protoc-gen-authorize)
-	authz, err := example.NewAuthorizer()
+	rules, err := example.NewAuthorizer()
 	if err != nil {
 		return err
 	}
+	// cache the decisions of methods that declare a cache key
+	authz := authorizer.NewCachingAuthorizer(rules, authorizer.WithCacheTTL(time.Minute))
 	// create a new grpc server with the authorizer interceptors
 	srv := grpc.NewServer(
 		grpc.UnaryInterceptor(
@@ -5,12 +5,20 @@ import (
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	"github.com/autom8ter/protoc-gen-authorize/authorizer"
+	"github.com/autom8ter/protoc-gen-authorize/authorizer/javascript"
 	"github.com/autom8ter/protoc-gen-authorize/example/gen/example"
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 )
 
 func Test(t *testing.T) {
@@ -73,6 +81,46 @@ func Test(t *testing.T) {
 			t.Fatalf("failed to call MetadataMatch: %v", err)
 		}
 	}
+	{
+		// permission denied on the second message: user.AccountIds.includes(request.AccountId)
+		stream, err := client.StreamMatch(context.Background())
+		if err != nil {
+			t.Fatalf("failed to call StreamMatch: %v", err)
+		}
+		for _, accountID := range []string{testUser.AccountIds[0], "123"} {
+			if err := stream.Send(&example.Request{
+				AccountId: accountID,
+				Message:   "hello",
+			}); err != nil {
+				break
+			}
+		}
+		if _, err := stream.CloseAndRecv(); err == nil {
+			t.Fatalf("expected error, got nil")
+		} else {
+			if status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("expected error code %v, got %v", codes.PermissionDenied, status.Code(err))
+			}
+		}
+	}
+	{
+		// authorized: user.AccountIds.includes(request.AccountId)
+		stream, err := client.StreamMatch(context.Background())
+		if err != nil {
+			t.Fatalf("failed to call StreamMatch: %v", err)
+		}
+		for _, accountID := range testUser.AccountIds {
+			if err := stream.Send(&example.Request{
+				AccountId: accountID,
+				Message:   "hello",
+			}); err != nil {
+				t.Fatalf("failed to send message: %v", err)
+			}
+		}
+		if _, err := stream.CloseAndRecv(); err != nil {
+			t.Fatalf("failed to call StreamMatch: %v", err)
+		}
+	}
 	{
 		// authorized: true
 		if _, err := client.AllowAll(context.Background(), &example.Request{
@@ -83,3 +131,325 @@ func Test(t *testing.T) {
 		}
 	}
 }
+
+func TestClientInterceptors(t *testing.T) {
+	authz, err := example.NewAuthorizer()
+	if err != nil {
+		t.Fatalf("failed to create authorizer: %v", err)
+	}
+	// nothing is listening on this address, so only requests allowed by the client interceptor reach the network
+	conn, err := grpc.Dial(":10043",
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(authorizer.UnaryClientInterceptor(authz, authorizer.WithUserExtractor(authorizer.DefaultUserExtractor))),
+		grpc.WithStreamInterceptor(authorizer.StreamClientInterceptor(authz, authorizer.WithUserExtractor(authorizer.DefaultUserExtractor))),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer conn.Close()
+	client := example.NewExampleServiceClient(conn)
+	ctx := authorizer.ContextWithUser(context.Background(), testUser)
+	{
+		// permission denied before the request is sent
+		if _, err := client.RequestMatch(ctx, &example.Request{
+			AccountId: "123",
+			Message:   "hello",
+		}); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected error code %v, got %v", codes.PermissionDenied, status.Code(err))
+		}
+	}
+	{
+		// authorized: user.AccountIds.includes(request.AccountId) && user.Roles.includes('admin')
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		if _, err := client.RequestMatch(ctx, &example.Request{
+			AccountId: testUser.AccountIds[0],
+			Message:   "hello",
+		}); status.Code(err) == codes.PermissionDenied {
+			t.Fatalf("expected request to be sent, got %v", err)
+		}
+	}
+}
+
+func TestAuditor(t *testing.T) {
+	authz, err := example.NewAuthorizer()
+	if err != nil {
+		t.Fatalf("failed to create authorizer: %v", err)
+	}
+	var events []*authorizer.AuditEvent
+	auditor := authorizer.AuditorFunc(func(ctx context.Context, event *authorizer.AuditEvent) {
+		events = append(events, event)
+	})
+	conn, err := grpc.Dial(":10044",
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(authorizer.UnaryClientInterceptor(authz,
+			authorizer.WithUserExtractor(authorizer.DefaultUserExtractor),
+			authorizer.WithAuditor(auditor),
+			authorizer.WithAuditMetadataKeys("x-account-id"),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer conn.Close()
+	client := example.NewExampleServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-account-id", "123", "authorization", "secret")
+	if _, err := client.RequestMatch(authorizer.ContextWithUser(ctx, testUser), &example.Request{
+		AccountId: "123",
+		Message:   "hello",
+	}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected error code %v, got %v", codes.PermissionDenied, status.Code(err))
+	}
+	if _, err := client.RequestMatch(ctx, &example.Request{}); err == nil {
+		t.Fatalf("expected user extraction error, got nil")
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 audit events, got %d", len(events))
+	}
+	denied := events[0]
+	if denied.Method != example.ExampleService_RequestMatch_FullMethodName {
+		t.Fatalf("expected method %s, got %s", example.ExampleService_RequestMatch_FullMethodName, denied.Method)
+	}
+	if denied.UserID != testUser.Id {
+		t.Fatalf("expected user id %s, got %s", testUser.Id, denied.UserID)
+	}
+	if denied.Decision.Allowed() || denied.Decision.Engine == "" {
+		t.Fatalf("expected engine deny decision, got %+v", denied.Decision)
+	}
+	if len(denied.Metadata) != 1 || denied.Metadata.Get("x-account-id")[0] != "123" {
+		t.Fatalf("expected only audited metadata keys, got %v", denied.Metadata)
+	}
+	if events[1].Err == nil || events[1].Decision != nil {
+		t.Fatalf("expected user extraction error event, got %+v", events[1])
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	authz, err := example.NewAuthorizer()
+	if err != nil {
+		t.Fatalf("failed to create authorizer: %v", err)
+	}
+	var events []*authorizer.DryRunEvent
+	conn, err := grpc.Dial(":10045",
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(authorizer.UnaryClientInterceptor(authz,
+			authorizer.WithUserExtractor(authorizer.DefaultUserExtractor),
+			authorizer.WithDryRun(func(ctx context.Context, event *authorizer.DryRunEvent) {
+				events = append(events, event)
+			}),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer conn.Close()
+	client := example.NewExampleServiceClient(conn)
+	ctx, cancel := context.WithTimeout(authorizer.ContextWithUser(context.Background(), testUser), 100*time.Millisecond)
+	defer cancel()
+	// the request would be denied, but it is sent to the server in dry run mode
+	if _, err := client.RequestMatch(ctx, &example.Request{
+		AccountId: "123",
+		Message:   "hello",
+	}); status.Code(err) == codes.PermissionDenied {
+		t.Fatalf("expected request to be sent, got %v", err)
+	}
+	if len(events) != 1 || events[0].Decision.Allowed() {
+		t.Fatalf("expected 1 would-be deny decision, got %+v", events)
+	}
+	{
+		// only methods whose RuleSet enables dry_run are not enforced
+		authz, err := javascript.NewJavascriptAuthorizer(map[string]*authorize.RuleSet{
+			example.ExampleService_RequestMatch_FullMethodName: {
+				Rules:  []*authorize.Rule{{Expression: "false"}},
+				DryRun: true,
+			},
+			example.ExampleService_MetadataMatch_FullMethodName: {
+				Rules: []*authorize.Rule{{Expression: "false"}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create authorizer: %v", err)
+		}
+		events = nil
+		conn, err := grpc.Dial(":10045",
+			grpc.WithInsecure(),
+			grpc.WithUnaryInterceptor(authorizer.UnaryClientInterceptor(authz,
+				authorizer.WithDryRunHandler(func(ctx context.Context, event *authorizer.DryRunEvent) {
+					events = append(events, event)
+				}),
+			)),
+		)
+		if err != nil {
+			t.Fatalf("failed to dial server: %v", err)
+		}
+		defer conn.Close()
+		client := example.NewExampleServiceClient(conn)
+		if _, err := client.RequestMatch(ctx, &example.Request{}); status.Code(err) == codes.PermissionDenied {
+			t.Fatalf("expected request to be sent, got %v", err)
+		}
+		if _, err := client.MetadataMatch(ctx, &example.Request{}); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected error code %v, got %v", codes.PermissionDenied, status.Code(err))
+		}
+		if len(events) != 1 || events[0].Method != example.ExampleService_RequestMatch_FullMethodName {
+			t.Fatalf("expected 1 dry run event, got %+v", events)
+		}
+	}
+}
+
+func TestShadowAuthorizer(t *testing.T) {
+	authz, err := example.NewAuthorizer()
+	if err != nil {
+		t.Fatalf("failed to create authorizer: %v", err)
+	}
+	candidate, err := javascript.NewJavascriptAuthorizer(map[string]*authorize.RuleSet{
+		example.ExampleService_RequestMatch_FullMethodName: {
+			Rules: []*authorize.Rule{{Expression: "user.Roles.includes('admin')"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create candidate authorizer: %v", err)
+	}
+	var events []*authorizer.ShadowEvent
+	conn, err := grpc.Dial(":10046",
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(authorizer.UnaryClientInterceptor(authz,
+			authorizer.WithUserExtractor(authorizer.DefaultUserExtractor),
+			authorizer.WithShadowAuthorizer(candidate, func(ctx context.Context, event *authorizer.ShadowEvent) {
+				events = append(events, event)
+			}),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer conn.Close()
+	client := example.NewExampleServiceClient(conn)
+	ctx, cancel := context.WithTimeout(authorizer.ContextWithUser(context.Background(), testUser), 100*time.Millisecond)
+	defer cancel()
+	// both authorizers allow the request
+	_, _ = client.RequestMatch(ctx, &example.Request{
+		AccountId: testUser.AccountIds[0],
+		Message:   "hello",
+	})
+	if len(events) != 0 {
+		t.Fatalf("expected no disagreements, got %+v", events)
+	}
+	// the enforced authorizer denies the request, but the candidate allows it
+	if _, err := client.RequestMatch(ctx, &example.Request{
+		AccountId: "123",
+		Message:   "hello",
+	}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected error code %v, got %v", codes.PermissionDenied, status.Code(err))
+	}
+	if len(events) != 1 || events[0].Enforced.Allowed() || !events[0].Candidate.Allowed() {
+		t.Fatalf("expected 1 disagreement, got %+v", events)
+	}
+}
+
+func TestDenial(t *testing.T) {
+	authz, err := example.NewAuthorizer()
+	if err != nil {
+		t.Fatalf("failed to create authorizer: %v", err)
+	}
+	// requests are denied by the client interceptor before they are sent, so no server is needed
+	conn, err := grpc.Dial(":10047",
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(authorizer.UnaryClientInterceptor(authz,
+			authorizer.WithUserExtractor(authorizer.DefaultUserExtractor),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer conn.Close()
+	client := example.NewExampleServiceClient(conn)
+	{
+		// unauthenticated: the context has no user
+		if _, err := client.RequestMatch(context.Background(), &example.Request{}); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("expected error code %v, got %v", codes.Unauthenticated, status.Code(err))
+		}
+	}
+	{
+		// custom denial of the user.IsSuspended rule
+		suspended := proto.Clone(testUser).(*example.User)
+		suspended.IsSuspended = true
+		_, err := client.RequestMatch(authorizer.ContextWithUser(context.Background(), suspended), &example.Request{
+			AccountId: testUser.AccountIds[0],
+		})
+		st := status.Convert(err)
+		if st.Code() != codes.PermissionDenied || st.Message() != "account suspended" {
+			t.Fatalf("expected custom denial, got %v", err)
+		}
+		if len(st.Details()) != 1 {
+			t.Fatalf("expected error info detail, got %v", st.Details())
+		}
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		if !ok || info.Reason != "ACCOUNT_SUSPENDED" || info.Domain != "example.com" {
+			t.Fatalf("unexpected error info detail: %v", st.Details()[0])
+		}
+	}
+	{
+		// default denial of the RuleSet
+		_, err := client.RequestMatch(authorizer.ContextWithUser(context.Background(), testUser), &example.Request{
+			AccountId: "123",
+		})
+		if st := status.Convert(err); st.Code() != codes.PermissionDenied || st.Message() != "authorizer: permission denied" || len(st.Details()) != 0 {
+			t.Fatalf("expected default denial, got %v", err)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	authz, err := example.NewAuthorizer(javascript.WithTracerProvider(provider))
+	if err != nil {
+		t.Fatalf("failed to create authorizer: %v", err)
+	}
+	// requests are denied by the client interceptor before they are sent, so no server is needed
+	conn, err := grpc.Dial(":10048",
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(authorizer.UnaryClientInterceptor(authz,
+			authorizer.WithUserExtractor(authorizer.DefaultUserExtractor),
+			authorizer.WithTracerProvider(provider),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer conn.Close()
+	client := example.NewExampleServiceClient(conn)
+	if _, err := client.RequestMatch(authorizer.ContextWithUser(context.Background(), testUser), &example.Request{
+		AccountId: "123",
+	}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected error code %v, got %v", codes.PermissionDenied, status.Code(err))
+	}
+	spans := map[string][]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = append(spans[span.Name], span)
+	}
+	if len(spans["authorizer.Authorize"]) != 1 || len(spans["javascript.Decide"]) != 1 {
+		t.Fatalf("expected an authorization span and a decision span, got %v", spans)
+	}
+	authorize := spans["authorizer.Authorize"][0]
+	decide := spans["javascript.Decide"][0]
+	if decide.Parent.SpanID() != authorize.SpanContext.SpanID() {
+		t.Fatalf("expected the decision span to be a child of the authorization span")
+	}
+	// user.IsSuspended, the account rule and the inherited user.IsSuperAdmin rule are evaluated
+	if len(spans["javascript.EvaluateRule"]) != 3 {
+		t.Fatalf("expected 3 rule spans, got %d", len(spans["javascript.EvaluateRule"]))
+	}
+	for _, span := range spans["javascript.EvaluateRule"] {
+		if span.Parent.SpanID() != decide.SpanContext.SpanID() {
+			t.Fatalf("expected rule spans to be children of the decision span")
+		}
+	}
+	attributes := attribute.NewSet(authorize.Attributes...)
+	if v, _ := attributes.Value(authorizer.AttributeDecision); v.AsString() != string(authorizer.EffectDeny) {
+		t.Fatalf("expected deny decision attribute, got %v", v.AsString())
+	}
+	if v, _ := attributes.Value(authorizer.AttributeEngine); v.AsString() != javascript.Engine {
+		t.Fatalf("expected engine attribute, got %v", v.AsString())
+	}
+}
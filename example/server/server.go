@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"io"
 
 	"google.golang.org/protobuf/types/known/emptypb"
 
@@ -27,3 +28,14 @@ func (e *exampleServer) MetadataMatch(ctx context.Context, request *example.Requ
 func (e *exampleServer) AllowAll(ctx context.Context, request *example.Request) (*emptypb.Empty, error) {
 	return &emptypb.Empty{}, nil
 }
+
+func (e *exampleServer) StreamMatch(stream example.ExampleService_StreamMatchServer) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err == io.EOF {
+				return stream.SendAndClose(&emptypb.Empty{})
+			}
+			return err
+		}
+	}
+}
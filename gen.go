@@ -0,0 +1,3 @@
+//go:generate buf generate ./proto
+
+package main
@@ -0,0 +1,823 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: authorize/authorize.proto
+
+package authorize
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Effect is the effect of a rule when its expression evaluates to true.
+type Effect int32
+
+const (
+	// EFFECT_UNSPECIFIED is treated as EFFECT_ALLOW.
+	Effect_EFFECT_UNSPECIFIED Effect = 0
+	// EFFECT_ALLOW authorizes the request when the rule's expression evaluates to true.
+	Effect_EFFECT_ALLOW Effect = 1
+	// EFFECT_DENY denies the request when the rule's expression evaluates to true.
+	Effect_EFFECT_DENY Effect = 2
+)
+
+// Enum value maps for Effect.
+var (
+	Effect_name = map[int32]string{
+		0: "EFFECT_UNSPECIFIED",
+		1: "EFFECT_ALLOW",
+		2: "EFFECT_DENY",
+	}
+	Effect_value = map[string]int32{
+		"EFFECT_UNSPECIFIED": 0,
+		"EFFECT_ALLOW":       1,
+		"EFFECT_DENY":        2,
+	}
+)
+
+func (x Effect) Enum() *Effect {
+	p := new(Effect)
+	*p = x
+	return p
+}
+
+func (x Effect) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Effect) Descriptor() protoreflect.EnumDescriptor {
+	return file_authorize_authorize_proto_enumTypes[0].Descriptor()
+}
+
+func (Effect) Type() protoreflect.EnumType {
+	return &file_authorize_authorize_proto_enumTypes[0]
+}
+
+func (x Effect) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Effect.Descriptor instead.
+func (Effect) EnumDescriptor() ([]byte, []int) {
+	return file_authorize_authorize_proto_rawDescGZIP(), []int{0}
+}
+
+// CombiningAlgorithm determines how the results of a RuleSet's rules are combined into a single decision.
+// If no rules evaluate to true, then the request is not authorized regardless of the algorithm.
+type CombiningAlgorithm int32
+
+const (
+	// COMBINING_ALGORITHM_UNSPECIFIED is treated as COMBINING_ALGORITHM_FIRST_APPLICABLE.
+	CombiningAlgorithm_COMBINING_ALGORITHM_UNSPECIFIED CombiningAlgorithm = 0
+	// The effect of the first rule (in order) that evaluates to true is applied.
+	CombiningAlgorithm_COMBINING_ALGORITHM_FIRST_APPLICABLE CombiningAlgorithm = 1
+	// The request is denied if any deny rule evaluates to true.
+	// Otherwise, the request is authorized if any allow rule evaluates to true.
+	CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES CombiningAlgorithm = 2
+	// The request is authorized if any allow rule evaluates to true.
+	// Otherwise, the request is denied.
+	CombiningAlgorithm_COMBINING_ALGORITHM_PERMIT_OVERRIDES CombiningAlgorithm = 3
+)
+
+// Enum value maps for CombiningAlgorithm.
+var (
+	CombiningAlgorithm_name = map[int32]string{
+		0: "COMBINING_ALGORITHM_UNSPECIFIED",
+		1: "COMBINING_ALGORITHM_FIRST_APPLICABLE",
+		2: "COMBINING_ALGORITHM_DENY_OVERRIDES",
+		3: "COMBINING_ALGORITHM_PERMIT_OVERRIDES",
+	}
+	CombiningAlgorithm_value = map[string]int32{
+		"COMBINING_ALGORITHM_UNSPECIFIED":      0,
+		"COMBINING_ALGORITHM_FIRST_APPLICABLE": 1,
+		"COMBINING_ALGORITHM_DENY_OVERRIDES":   2,
+		"COMBINING_ALGORITHM_PERMIT_OVERRIDES": 3,
+	}
+)
+
+func (x CombiningAlgorithm) Enum() *CombiningAlgorithm {
+	p := new(CombiningAlgorithm)
+	*p = x
+	return p
+}
+
+func (x CombiningAlgorithm) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CombiningAlgorithm) Descriptor() protoreflect.EnumDescriptor {
+	return file_authorize_authorize_proto_enumTypes[1].Descriptor()
+}
+
+func (CombiningAlgorithm) Type() protoreflect.EnumType {
+	return &file_authorize_authorize_proto_enumTypes[1]
+}
+
+func (x CombiningAlgorithm) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CombiningAlgorithm.Descriptor instead.
+func (CombiningAlgorithm) EnumDescriptor() ([]byte, []int) {
+	return file_authorize_authorize_proto_rawDescGZIP(), []int{1}
+}
+
+// Inheritance determines how a RuleSet is merged with the rules inherited from its service or file.
+type Inheritance int32
+
+const (
+	// INHERITANCE_UNSPECIFIED is treated as INHERITANCE_EXTEND.
+	Inheritance_INHERITANCE_UNSPECIFIED Inheritance = 0
+	// The inherited deny rules are evaluated first, then the RuleSet's rules, then the inherited allow rules:
+	// file deny rules, service deny rules, method rules, service allow rules and file allow rules.
+	// Inherited deny rules therefore always apply, even with COMBINING_ALGORITHM_FIRST_APPLICABLE (the default).
+	// The RuleSet's algorithm is used if it is set, otherwise the inherited algorithm is used.
+	Inheritance_INHERITANCE_EXTEND Inheritance = 1
+	// The RuleSet's rules replace the inherited rules.
+	Inheritance_INHERITANCE_REPLACE Inheritance = 2
+)
+
+// Enum value maps for Inheritance.
+var (
+	Inheritance_name = map[int32]string{
+		0: "INHERITANCE_UNSPECIFIED",
+		1: "INHERITANCE_EXTEND",
+		2: "INHERITANCE_REPLACE",
+	}
+	Inheritance_value = map[string]int32{
+		"INHERITANCE_UNSPECIFIED": 0,
+		"INHERITANCE_EXTEND":      1,
+		"INHERITANCE_REPLACE":     2,
+	}
+)
+
+func (x Inheritance) Enum() *Inheritance {
+	p := new(Inheritance)
+	*p = x
+	return p
+}
+
+func (x Inheritance) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Inheritance) Descriptor() protoreflect.EnumDescriptor {
+	return file_authorize_authorize_proto_enumTypes[2].Descriptor()
+}
+
+func (Inheritance) Type() protoreflect.EnumType {
+	return &file_authorize_authorize_proto_enumTypes[2]
+}
+
+func (x Inheritance) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Inheritance.Descriptor instead.
+func (Inheritance) EnumDescriptor() ([]byte, []int) {
+	return file_authorize_authorize_proto_rawDescGZIP(), []int{2}
+}
+
+type RuleSet struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The rules to apply to a request.
+	Rules []*Rule `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	// The algorithm used to combine the results of the rules into a single decision.
+	Algorithm CombiningAlgorithm `protobuf:"varint,2,opt,name=algorithm,proto3,enum=authorize.CombiningAlgorithm" json:"algorithm,omitempty"`
+	// How the rules are merged with the rules inherited from the service or file (defaults to extend).
+	Inheritance Inheritance `protobuf:"varint,3,opt,name=inheritance,proto3,enum=authorize.Inheritance" json:"inheritance,omitempty"`
+	// If true, the rules of client-streaming and bidi-streaming methods are evaluated against every message received
+	// from the client (in addition to once when the stream is opened, with a null request) and the stream is aborted
+	// on the first denied message.
+	// The message_index variable is the index of the message in the stream.
+	AuthorizeStreamMessages bool `protobuf:"varint,4,opt,name=authorize_stream_messages,json=authorizeStreamMessages,proto3" json:"authorize_stream_messages,omitempty"`
+	// If true, the rules are evaluated and the would-be decision is reported to the interceptor's dry run handler and auditor,
+	// and the request is allowed even if the rules deny it. User extraction errors are still returned.
+	// This is useful for rolling out new rules without enforcing them.
+	DryRun bool `protobuf:"varint,5,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	// The error returned when the rule set denies a request (defaults to PERMISSION_DENIED "authorizer: permission denied").
+	// Deny rules may override it with their own denial.
+	Denial *Denial `protobuf:"bytes,6,opt,name=denial,proto3" json:"denial,omitempty"`
+	// If set, the decisions of the method are cached by authorizers wrapped with authorizer.NewCachingAuthorizer.
+	// The rules must only depend on the user, the method and the inputs declared in the cache key.
+	Cache *DecisionCache `protobuf:"bytes,7,opt,name=cache,proto3" json:"cache,omitempty"`
+}
+
+func (x *RuleSet) Reset() {
+	*x = RuleSet{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_authorize_authorize_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RuleSet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuleSet) ProtoMessage() {}
+
+func (x *RuleSet) ProtoReflect() protoreflect.Message {
+	mi := &file_authorize_authorize_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuleSet.ProtoReflect.Descriptor instead.
+func (*RuleSet) Descriptor() ([]byte, []int) {
+	return file_authorize_authorize_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RuleSet) GetRules() []*Rule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *RuleSet) GetAlgorithm() CombiningAlgorithm {
+	if x != nil {
+		return x.Algorithm
+	}
+	return CombiningAlgorithm_COMBINING_ALGORITHM_UNSPECIFIED
+}
+
+func (x *RuleSet) GetInheritance() Inheritance {
+	if x != nil {
+		return x.Inheritance
+	}
+	return Inheritance_INHERITANCE_UNSPECIFIED
+}
+
+func (x *RuleSet) GetAuthorizeStreamMessages() bool {
+	if x != nil {
+		return x.AuthorizeStreamMessages
+	}
+	return false
+}
+
+func (x *RuleSet) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *RuleSet) GetDenial() *Denial {
+	if x != nil {
+		return x.Denial
+	}
+	return nil
+}
+
+func (x *RuleSet) GetCache() *DecisionCache {
+	if x != nil {
+		return x.Cache
+	}
+	return nil
+}
+
+// DecisionCache declares the inputs of a method's rules that are part of the decision cache key, in addition to the
+// identity of the user and the method.
+type DecisionCache struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The request fields that are part of the cache key, as dot separated paths of proto field names (ex: account_id, account.id).
+	RequestFields []string `protobuf:"bytes,1,rep,name=request_fields,json=requestFields,proto3" json:"request_fields,omitempty"`
+	// The metadata keys that are part of the cache key.
+	MetadataKeys []string `protobuf:"bytes,2,rep,name=metadata_keys,json=metadataKeys,proto3" json:"metadata_keys,omitempty"`
+}
+
+func (x *DecisionCache) Reset() {
+	*x = DecisionCache{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_authorize_authorize_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DecisionCache) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecisionCache) ProtoMessage() {}
+
+func (x *DecisionCache) ProtoReflect() protoreflect.Message {
+	mi := &file_authorize_authorize_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecisionCache.ProtoReflect.Descriptor instead.
+func (*DecisionCache) Descriptor() ([]byte, []int) {
+	return file_authorize_authorize_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DecisionCache) GetRequestFields() []string {
+	if x != nil {
+		return x.RequestFields
+	}
+	return nil
+}
+
+func (x *DecisionCache) GetMetadataKeys() []string {
+	if x != nil {
+		return x.MetadataKeys
+	}
+	return nil
+}
+
+// Rule is a single rule that is used to authorize a request.
+type Rule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The expression to evaluate. This is a string that is evaluated against
+	// the request. The expression must evaluate to a boolean value.
+	// If the expression evaluates to true, then the rule's effect is applied to the request.
+	Expression string `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	// The effect of the rule when the expression evaluates to true (defaults to allow).
+	Effect Effect `protobuf:"varint,2,opt,name=effect,proto3,enum=authorize.Effect" json:"effect,omitempty"`
+	// The error returned when this rule denies a request. It overrides the RuleSet's denial and is only used by deny rules.
+	Denial *Denial `protobuf:"bytes,3,opt,name=denial,proto3" json:"denial,omitempty"`
+	// The maximum runtime cost of evaluating the expression (CEL only). It overrides the cost limit of the CEL authorizer,
+	// and the plugin rejects expressions whose estimated minimum cost exceeds it. 0 uses the authorizer's cost limit.
+	CostLimit uint64 `protobuf:"varint,4,opt,name=cost_limit,json=costLimit,proto3" json:"cost_limit,omitempty"`
+	// An optional name that identifies the rule in decisions, audit logs, traces and metrics (ex: suspended-users).
+	// Rules without a name are identified by their index in the RuleSet.
+	Name string `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *Rule) Reset() {
+	*x = Rule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_authorize_authorize_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Rule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rule) ProtoMessage() {}
+
+func (x *Rule) ProtoReflect() protoreflect.Message {
+	mi := &file_authorize_authorize_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rule.ProtoReflect.Descriptor instead.
+func (*Rule) Descriptor() ([]byte, []int) {
+	return file_authorize_authorize_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Rule) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *Rule) GetEffect() Effect {
+	if x != nil {
+		return x.Effect
+	}
+	return Effect_EFFECT_UNSPECIFIED
+}
+
+func (x *Rule) GetDenial() *Denial {
+	if x != nil {
+		return x.Denial
+	}
+	return nil
+}
+
+func (x *Rule) GetCostLimit() uint64 {
+	if x != nil {
+		return x.CostLimit
+	}
+	return 0
+}
+
+func (x *Rule) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Denial customizes the error returned to the client when a request is denied.
+type Denial struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The message of the error (defaults to "authorizer: permission denied").
+	Message string `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// The gRPC status code of the error (ex: 16 for UNAUTHENTICATED). Defaults to 7 (PERMISSION_DENIED).
+	Code uint32 `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	// The reason of the google.rpc.ErrorInfo detail attached to the error (ex: ACCOUNT_SUSPENDED).
+	// The detail is only attached if the reason, domain or metadata is set.
+	Reason string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	// The domain of the google.rpc.ErrorInfo detail (ex: example.com).
+	Domain string `protobuf:"bytes,4,opt,name=domain,proto3" json:"domain,omitempty"`
+	// The metadata of the google.rpc.ErrorInfo detail.
+	Metadata map[string]string `protobuf:"bytes,5,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *Denial) Reset() {
+	*x = Denial{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_authorize_authorize_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Denial) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Denial) ProtoMessage() {}
+
+func (x *Denial) ProtoReflect() protoreflect.Message {
+	mi := &file_authorize_authorize_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Denial.ProtoReflect.Descriptor instead.
+func (*Denial) Descriptor() ([]byte, []int) {
+	return file_authorize_authorize_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Denial) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Denial) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Denial) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Denial) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *Denial) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+var file_authorize_authorize_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*RuleSet)(nil),
+		Field:         73902,
+		Name:          "authorize.rules",
+		Tag:           "bytes,73902,opt,name=rules",
+		Filename:      "authorize/authorize.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*RuleSet)(nil),
+		Field:         73902,
+		Name:          "authorize.service_rules",
+		Tag:           "bytes,73902,opt,name=service_rules",
+		Filename:      "authorize/authorize.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FileOptions)(nil),
+		ExtensionType: (*RuleSet)(nil),
+		Field:         73902,
+		Name:          "authorize.file_rules",
+		Tag:           "bytes,73902,opt,name=file_rules",
+		Filename:      "authorize/authorize.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// Rules to apply to requests to this method.
+	// The rules are combined into a single decision using the RuleSet's combining algorithm.
+	// By default, the effect of the first rule that evaluates to true is applied.
+	// If no rules evaluate to true, then the request is not authorized.
+	//
+	// optional authorize.RuleSet rules = 73902;
+	E_Rules = &file_authorize_authorize_proto_extTypes[0]
+)
+
+// Extension fields to descriptorpb.ServiceOptions.
+var (
+	// Rules inherited by every method of the service.
+	// Methods may extend or replace the inherited rules with the RuleSet's inheritance field.
+	// Extended method rules are evaluated after the service deny rules and before the service allow rules (see INHERITANCE_EXTEND).
+	//
+	// optional authorize.RuleSet service_rules = 73902;
+	E_ServiceRules = &file_authorize_authorize_proto_extTypes[1]
+)
+
+// Extension fields to descriptorpb.FileOptions.
+var (
+	// Rules inherited by every method of every service in the file.
+	// Services and methods may extend or replace the inherited rules with the RuleSet's inheritance field.
+	// Extended method and service rules are evaluated after the file deny rules and before the file allow rules (see INHERITANCE_EXTEND).
+	//
+	// optional authorize.RuleSet file_rules = 73902;
+	E_FileRules = &file_authorize_authorize_proto_extTypes[2]
+)
+
+var File_authorize_authorize_proto protoreflect.FileDescriptor
+
+var file_authorize_authorize_proto_rawDesc = []byte{
+	0x0a, 0x19, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2f, 0x61, 0x75, 0x74, 0x68,
+	0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x61, 0x75, 0x74,
+	0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xd7, 0x02, 0x0a, 0x07, 0x52, 0x75, 0x6c,
+	0x65, 0x53, 0x65, 0x74, 0x12, 0x25, 0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e,
+	0x52, 0x75, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x12, 0x3b, 0x0a, 0x09, 0x61,
+	0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1d,
+	0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x43, 0x6f, 0x6d, 0x62, 0x69,
+	0x6e, 0x69, 0x6e, 0x67, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x52, 0x09, 0x61,
+	0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x38, 0x0a, 0x0b, 0x69, 0x6e, 0x68, 0x65,
+	0x72, 0x69, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e,
+	0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x49, 0x6e, 0x68, 0x65, 0x72, 0x69,
+	0x74, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x0b, 0x69, 0x6e, 0x68, 0x65, 0x72, 0x69, 0x74, 0x61, 0x6e,
+	0x63, 0x65, 0x12, 0x3a, 0x0a, 0x19, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x5f,
+	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x17, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x12, 0x17,
+	0x0a, 0x07, 0x64, 0x72, 0x79, 0x5f, 0x72, 0x75, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x06, 0x64, 0x72, 0x79, 0x52, 0x75, 0x6e, 0x12, 0x29, 0x0a, 0x06, 0x64, 0x65, 0x6e, 0x69, 0x61,
+	0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72,
+	0x69, 0x7a, 0x65, 0x2e, 0x44, 0x65, 0x6e, 0x69, 0x61, 0x6c, 0x52, 0x06, 0x64, 0x65, 0x6e, 0x69,
+	0x61, 0x6c, 0x12, 0x2e, 0x0a, 0x05, 0x63, 0x61, 0x63, 0x68, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x18, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x44, 0x65,
+	0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x43, 0x61, 0x63, 0x68, 0x65, 0x52, 0x05, 0x63, 0x61, 0x63,
+	0x68, 0x65, 0x22, 0x5b, 0x0a, 0x0d, 0x44, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x43, 0x61,
+	0x63, 0x68, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x66,
+	0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x72, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x6d, 0x65,
+	0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0c, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x4b, 0x65, 0x79, 0x73, 0x22,
+	0xaf, 0x01, 0x0a, 0x04, 0x52, 0x75, 0x6c, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x78,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a, 0x06, 0x65, 0x66, 0x66, 0x65,
+	0x63, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f,
+	0x72, 0x69, 0x7a, 0x65, 0x2e, 0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x52, 0x06, 0x65, 0x66, 0x66,
+	0x65, 0x63, 0x74, 0x12, 0x29, 0x0a, 0x06, 0x64, 0x65, 0x6e, 0x69, 0x61, 0x6c, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e,
+	0x44, 0x65, 0x6e, 0x69, 0x61, 0x6c, 0x52, 0x06, 0x64, 0x65, 0x6e, 0x69, 0x61, 0x6c, 0x12, 0x1d,
+	0x0a, 0x0a, 0x63, 0x6f, 0x73, 0x74, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x09, 0x63, 0x6f, 0x73, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x22, 0xe0, 0x01, 0x0a, 0x06, 0x44, 0x65, 0x6e, 0x69, 0x61, 0x6c, 0x12, 0x18, 0x0a, 0x07,
+	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x12, 0x3b, 0x0a, 0x08, 0x6d, 0x65,
+	0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x61,
+	0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x44, 0x65, 0x6e, 0x69, 0x61, 0x6c, 0x2e,
+	0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x6d,
+	0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x1a, 0x3b, 0x0a, 0x0d, 0x4d, 0x65, 0x74, 0x61, 0x64,
+	0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x2a, 0x43, 0x0a, 0x06, 0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x12, 0x16,
+	0x0a, 0x12, 0x45, 0x46, 0x46, 0x45, 0x43, 0x54, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49,
+	0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x45, 0x46, 0x46, 0x45, 0x43, 0x54,
+	0x5f, 0x41, 0x4c, 0x4c, 0x4f, 0x57, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x45, 0x46, 0x46, 0x45,
+	0x43, 0x54, 0x5f, 0x44, 0x45, 0x4e, 0x59, 0x10, 0x02, 0x2a, 0xb5, 0x01, 0x0a, 0x12, 0x43, 0x6f,
+	0x6d, 0x62, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d,
+	0x12, 0x23, 0x0a, 0x1f, 0x43, 0x4f, 0x4d, 0x42, 0x49, 0x4e, 0x49, 0x4e, 0x47, 0x5f, 0x41, 0x4c,
+	0x47, 0x4f, 0x52, 0x49, 0x54, 0x48, 0x4d, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46,
+	0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x28, 0x0a, 0x24, 0x43, 0x4f, 0x4d, 0x42, 0x49, 0x4e, 0x49,
+	0x4e, 0x47, 0x5f, 0x41, 0x4c, 0x47, 0x4f, 0x52, 0x49, 0x54, 0x48, 0x4d, 0x5f, 0x46, 0x49, 0x52,
+	0x53, 0x54, 0x5f, 0x41, 0x50, 0x50, 0x4c, 0x49, 0x43, 0x41, 0x42, 0x4c, 0x45, 0x10, 0x01, 0x12,
+	0x26, 0x0a, 0x22, 0x43, 0x4f, 0x4d, 0x42, 0x49, 0x4e, 0x49, 0x4e, 0x47, 0x5f, 0x41, 0x4c, 0x47,
+	0x4f, 0x52, 0x49, 0x54, 0x48, 0x4d, 0x5f, 0x44, 0x45, 0x4e, 0x59, 0x5f, 0x4f, 0x56, 0x45, 0x52,
+	0x52, 0x49, 0x44, 0x45, 0x53, 0x10, 0x02, 0x12, 0x28, 0x0a, 0x24, 0x43, 0x4f, 0x4d, 0x42, 0x49,
+	0x4e, 0x49, 0x4e, 0x47, 0x5f, 0x41, 0x4c, 0x47, 0x4f, 0x52, 0x49, 0x54, 0x48, 0x4d, 0x5f, 0x50,
+	0x45, 0x52, 0x4d, 0x49, 0x54, 0x5f, 0x4f, 0x56, 0x45, 0x52, 0x52, 0x49, 0x44, 0x45, 0x53, 0x10,
+	0x03, 0x2a, 0x5b, 0x0a, 0x0b, 0x49, 0x6e, 0x68, 0x65, 0x72, 0x69, 0x74, 0x61, 0x6e, 0x63, 0x65,
+	0x12, 0x1b, 0x0a, 0x17, 0x49, 0x4e, 0x48, 0x45, 0x52, 0x49, 0x54, 0x41, 0x4e, 0x43, 0x45, 0x5f,
+	0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x16, 0x0a,
+	0x12, 0x49, 0x4e, 0x48, 0x45, 0x52, 0x49, 0x54, 0x41, 0x4e, 0x43, 0x45, 0x5f, 0x45, 0x58, 0x54,
+	0x45, 0x4e, 0x44, 0x10, 0x01, 0x12, 0x17, 0x0a, 0x13, 0x49, 0x4e, 0x48, 0x45, 0x52, 0x49, 0x54,
+	0x41, 0x4e, 0x43, 0x45, 0x5f, 0x52, 0x45, 0x50, 0x4c, 0x41, 0x43, 0x45, 0x10, 0x02, 0x3a, 0x4a,
+	0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64,
+	0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xae, 0xc1, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x12, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x52, 0x75, 0x6c, 0x65,
+	0x53, 0x65, 0x74, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x3a, 0x5a, 0x0a, 0x0d, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x12, 0x1f, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xae, 0xc1, 0x04,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65,
+	0x2e, 0x52, 0x75, 0x6c, 0x65, 0x53, 0x65, 0x74, 0x52, 0x0c, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x3a, 0x51, 0x0a, 0x0a, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x72,
+	0x75, 0x6c, 0x65, 0x73, 0x12, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x18, 0xae, 0xc1, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x61, 0x75, 0x74,
+	0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x53, 0x65, 0x74, 0x52, 0x09,
+	0x66, 0x69, 0x6c, 0x65, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x42, 0x43, 0x5a, 0x41, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x75, 0x74, 0x6f, 0x6d, 0x38, 0x74, 0x65,
+	0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x2d, 0x67, 0x65, 0x6e, 0x2d, 0x61, 0x75, 0x74,
+	0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x61, 0x75, 0x74, 0x68, 0x6f,
+	0x72, 0x69, 0x7a, 0x65, 0x3b, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_authorize_authorize_proto_rawDescOnce sync.Once
+	file_authorize_authorize_proto_rawDescData = file_authorize_authorize_proto_rawDesc
+)
+
+func file_authorize_authorize_proto_rawDescGZIP() []byte {
+	file_authorize_authorize_proto_rawDescOnce.Do(func() {
+		file_authorize_authorize_proto_rawDescData = protoimpl.X.CompressGZIP(file_authorize_authorize_proto_rawDescData)
+	})
+	return file_authorize_authorize_proto_rawDescData
+}
+
+var file_authorize_authorize_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_authorize_authorize_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_authorize_authorize_proto_goTypes = []interface{}{
+	(Effect)(0),                         // 0: authorize.Effect
+	(CombiningAlgorithm)(0),             // 1: authorize.CombiningAlgorithm
+	(Inheritance)(0),                    // 2: authorize.Inheritance
+	(*RuleSet)(nil),                     // 3: authorize.RuleSet
+	(*DecisionCache)(nil),               // 4: authorize.DecisionCache
+	(*Rule)(nil),                        // 5: authorize.Rule
+	(*Denial)(nil),                      // 6: authorize.Denial
+	nil,                                 // 7: authorize.Denial.MetadataEntry
+	(*descriptorpb.MethodOptions)(nil),  // 8: google.protobuf.MethodOptions
+	(*descriptorpb.ServiceOptions)(nil), // 9: google.protobuf.ServiceOptions
+	(*descriptorpb.FileOptions)(nil),    // 10: google.protobuf.FileOptions
+}
+var file_authorize_authorize_proto_depIdxs = []int32{
+	5,  // 0: authorize.RuleSet.rules:type_name -> authorize.Rule
+	1,  // 1: authorize.RuleSet.algorithm:type_name -> authorize.CombiningAlgorithm
+	2,  // 2: authorize.RuleSet.inheritance:type_name -> authorize.Inheritance
+	6,  // 3: authorize.RuleSet.denial:type_name -> authorize.Denial
+	4,  // 4: authorize.RuleSet.cache:type_name -> authorize.DecisionCache
+	0,  // 5: authorize.Rule.effect:type_name -> authorize.Effect
+	6,  // 6: authorize.Rule.denial:type_name -> authorize.Denial
+	7,  // 7: authorize.Denial.metadata:type_name -> authorize.Denial.MetadataEntry
+	8,  // 8: authorize.rules:extendee -> google.protobuf.MethodOptions
+	9,  // 9: authorize.service_rules:extendee -> google.protobuf.ServiceOptions
+	10, // 10: authorize.file_rules:extendee -> google.protobuf.FileOptions
+	3,  // 11: authorize.rules:type_name -> authorize.RuleSet
+	3,  // 12: authorize.service_rules:type_name -> authorize.RuleSet
+	3,  // 13: authorize.file_rules:type_name -> authorize.RuleSet
+	14, // [14:14] is the sub-list for method output_type
+	14, // [14:14] is the sub-list for method input_type
+	11, // [11:14] is the sub-list for extension type_name
+	8,  // [8:11] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_authorize_authorize_proto_init() }
+func file_authorize_authorize_proto_init() {
+	if File_authorize_authorize_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_authorize_authorize_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RuleSet); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_authorize_authorize_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DecisionCache); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_authorize_authorize_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Rule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_authorize_authorize_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Denial); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_authorize_authorize_proto_rawDesc,
+			NumEnums:      3,
+			NumMessages:   5,
+			NumExtensions: 3,
+			NumServices:   0,
+		},
+		GoTypes:           file_authorize_authorize_proto_goTypes,
+		DependencyIndexes: file_authorize_authorize_proto_depIdxs,
+		EnumInfos:         file_authorize_authorize_proto_enumTypes,
+		MessageInfos:      file_authorize_authorize_proto_msgTypes,
+		ExtensionInfos:    file_authorize_authorize_proto_extTypes,
+	}.Build()
+	File_authorize_authorize_proto = out.File
+	file_authorize_authorize_proto_rawDesc = nil
+	file_authorize_authorize_proto_goTypes = nil
+	file_authorize_authorize_proto_depIdxs = nil
+}
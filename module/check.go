@@ -0,0 +1,307 @@
+package module
+
+import (
+	"fmt"
+
+	exprlang "github.com/expr-lang/expr"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	pgs "github.com/lyft/protoc-gen-star"
+	pgsgo "github.com/lyft/protoc-gen-star/lang/go"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/autom8ter/protoc-gen-authorize/authorizer"
+	authzcel "github.com/autom8ter/protoc-gen-authorize/authorizer/cel"
+	authzexpr "github.com/autom8ter/protoc-gen-authorize/authorizer/expr"
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// ruleChecker checks the rule expressions of a method during code generation
+type ruleChecker interface {
+	check(method pgs.Method, rule *authorize.Rule) error
+}
+
+// exprChecker compiles expr rule expressions during code generation. The request and user are untyped, so only the
+// syntax and the injected variables of expressions are checked. Expressions that use the variables or functions of
+// expr.WithVariables or expr.WithOptions require the type_check=false plugin option
+type exprChecker struct{}
+
+func (exprChecker) check(method pgs.Method, rule *authorize.Rule) error {
+	if rule.GetExpression() == authorizer.WildcardExpression {
+		return nil
+	}
+	_, err := exprlang.Compile(rule.GetExpression(), exprlang.Env(authzexpr.Variables()), exprlang.AsBool())
+	return err
+}
+
+// celChecker type checks CEL rule expressions against the input message of a method during code generation
+// so that invalid expressions fail the build instead of the first request
+type celChecker struct {
+	ctx  pgsgo.Context
+	user pgs.Message
+	// files is set when proto_messages is enabled and expressions are checked against the proto messages
+	files *protoregistry.Files
+	envs  map[string]*cel.Env
+}
+
+func newCelChecker(ctx pgsgo.Context, user pgs.Message, files *protoregistry.Files) *celChecker {
+	return &celChecker{
+		ctx:   ctx,
+		user:  user,
+		files: files,
+		envs:  map[string]*cel.Env{},
+	}
+}
+
+// check parses and type checks the rule's expression against the method's input message and the configured user message
+func (c *celChecker) check(method pgs.Method, rule *authorize.Rule) error {
+	if rule.GetExpression() == authorizer.WildcardExpression {
+		return nil
+	}
+	env, err := c.env(method.Input())
+	if err != nil {
+		return err
+	}
+	ast, issues := env.Compile(rule.GetExpression())
+	if issues != nil && issues.Err() != nil {
+		return issues.Err()
+	}
+	if limit := rule.GetCostLimit(); limit > 0 {
+		estimate, err := env.EstimateCost(ast, costEstimator{})
+		if err != nil {
+			return fmt.Errorf("failed to estimate cost: %v", err)
+		}
+		// the maximum cost is unbounded for expressions over lists and maps of unknown size, so only expressions
+		// that always exceed the limit are rejected
+		if estimate.Min > limit {
+			return fmt.Errorf("estimated minimum cost %d exceeds cost_limit %d", estimate.Min, limit)
+		}
+	}
+	return nil
+}
+
+// costEstimator uses the default size and call cost estimates of the CEL checker
+type costEstimator struct{}
+
+func (costEstimator) EstimateSize(element checker.AstNode) *checker.SizeEstimate {
+	return nil
+}
+
+func (costEstimator) EstimateCallCost(function, overloadID string, target *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
+
+func (c *celChecker) env(input pgs.Message) (*cel.Env, error) {
+	name := input.FullyQualifiedName()
+	if env, ok := c.envs[name]; ok {
+		return env, nil
+	}
+	if c.files != nil {
+		env, err := c.protoEnv(input)
+		if err != nil {
+			return nil, err
+		}
+		c.envs[name] = env
+		return env, nil
+	}
+	registry, err := types.NewRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cel type registry: %v", err)
+	}
+	provider := &goFieldProvider{
+		Provider: registry,
+		ctx:      c.ctx,
+		messages: map[string]pgs.Message{
+			typeName(input): input,
+		},
+	}
+	user := authzcel.MapType
+	if c.user != nil {
+		provider.messages[typeName(c.user)] = c.user
+		user = cel.ObjectType(typeName(c.user))
+	}
+	env, err := cel.NewEnv(append(
+		authzcel.Variables(cel.ObjectType(typeName(input)), user),
+		cel.CustomTypeProvider(provider),
+		authzcel.Library(),
+	)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cel env: %v", err)
+	}
+	c.envs[name] = env
+	return env, nil
+}
+
+// protoEnv mirrors the env of the CEL authorizer at runtime when cel.WithProtoMessages is enabled
+func (c *celChecker) protoEnv(input pgs.Message) (*cel.Env, error) {
+	request, err := c.files.FindDescriptorByName(protoreflect.FullName(typeName(input)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message %s: %v", typeName(input), err)
+	}
+	opts := []cel.EnvOption{
+		cel.TypeDescs(request.ParentFile()),
+		authzcel.Library(),
+	}
+	user := cel.DynType
+	if c.user != nil {
+		desc, err := c.files.FindDescriptorByName(protoreflect.FullName(typeName(c.user)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find message %s: %v", typeName(c.user), err)
+		}
+		opts = append(opts, cel.TypeDescs(desc.ParentFile()))
+		user = cel.ObjectType(typeName(c.user))
+	}
+	env, err := cel.NewEnv(append(opts, authzcel.Variables(cel.ObjectType(typeName(input)), user)...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cel env: %v", err)
+	}
+	return env, nil
+}
+
+// protoFiles builds a registry of the proto files in the code generation request
+func protoFiles(packages map[string]pgs.Package) (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, pkg := range packages {
+		for _, f := range pkg.Files() {
+			set.File = append(set.File, f.Descriptor())
+		}
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build proto registry: %v", err)
+	}
+	return files, nil
+}
+
+// typeName returns the CEL type name of a message (the fully qualified name without the leading dot)
+func typeName(msg pgs.Message) string {
+	return msg.FullyQualifiedName()[1:]
+}
+
+// goFieldProvider is a CEL type provider that exposes the top level fields of proto messages by their go field names.
+// The CEL authorizer decodes the request and user into maps keyed by go field names before evaluating expressions,
+// so expressions reference fields like request.AccountId instead of request.account_id
+type goFieldProvider struct {
+	types.Provider
+	ctx      pgsgo.Context
+	messages map[string]pgs.Message
+}
+
+func (p *goFieldProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, ok := p.messages[structType]; ok {
+		return types.NewTypeTypeWithParam(types.NewObjectType(structType)), true
+	}
+	return p.Provider.FindStructType(structType)
+}
+
+func (p *goFieldProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	msg, ok := p.messages[structType]
+	if !ok {
+		return p.Provider.FindStructFieldNames(structType)
+	}
+	var names []string
+	for name := range p.fields(msg) {
+		names = append(names, name)
+	}
+	return names, true
+}
+
+func (p *goFieldProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	msg, ok := p.messages[structType]
+	if !ok {
+		return p.Provider.FindStructFieldType(structType, fieldName)
+	}
+	t, ok := p.fields(msg)[fieldName]
+	if !ok {
+		return nil, false
+	}
+	return &types.FieldType{
+		Type: t,
+		IsSet: func(target any) bool {
+			return true
+		},
+		GetFrom: func(target any) (any, error) {
+			return nil, fmt.Errorf("unsupported field access: %s.%s", structType, fieldName)
+		},
+	}, true
+}
+
+func (p *goFieldProvider) NewValue(structType string, fields map[string]ref.Val) ref.Val {
+	return p.Provider.NewValue(structType, fields)
+}
+
+// fields returns the CEL types of the message's go struct fields keyed by go field name
+func (p *goFieldProvider) fields(msg pgs.Message) map[string]*types.Type {
+	fields := map[string]*types.Type{}
+	for _, f := range msg.NonOneOfFields() {
+		fields[p.ctx.Name(f).String()] = fieldType(f.Type())
+	}
+	// proto3 optional fields are pointers and oneofs are interfaces in the go struct
+	for _, f := range msg.SyntheticOneOfFields() {
+		fields[p.ctx.Name(f).String()] = types.DynType
+	}
+	for _, o := range msg.RealOneOfs() {
+		fields[p.ctx.Name(o).String()] = types.DynType
+	}
+	return fields
+}
+
+func fieldType(ft pgs.FieldType) *types.Type {
+	switch {
+	case ft.IsMap():
+		return types.NewMapType(scalarType(ft.Key().ProtoType()), scalarType(ft.Element().ProtoType()))
+	case ft.IsRepeated():
+		return types.NewListType(scalarType(ft.Element().ProtoType()))
+	default:
+		return scalarType(ft.ProtoType())
+	}
+}
+
+func scalarType(pt pgs.ProtoType) *types.Type {
+	switch pt {
+	case pgs.StringT:
+		return types.StringType
+	case pgs.BoolT:
+		return types.BoolType
+	case pgs.BytesT:
+		return types.BytesType
+	case pgs.DoubleT, pgs.FloatT:
+		return types.DoubleType
+	case pgs.Int32T, pgs.Int64T, pgs.SInt32, pgs.SInt64, pgs.SFixed32, pgs.SFixed64, pgs.EnumT:
+		return types.IntType
+	case pgs.UInt32T, pgs.UInt64T, pgs.Fixed32T, pgs.Fixed64T:
+		return types.UintType
+	default:
+		// nested messages are not decoded into maps, so their fields can't be type checked
+		return types.DynType
+	}
+}
+
+// sourceLocation returns the file:line:column of the entity at the given path in the file's source code info
+func sourceLocation(f pgs.File, path ...int32) string {
+	for ; len(path) > 0; path = path[:len(path)-1] {
+		for _, loc := range f.Descriptor().GetSourceCodeInfo().GetLocation() {
+			if equalPaths(loc.GetPath(), path) && len(loc.GetSpan()) >= 2 {
+				return fmt.Sprintf("%s:%d:%d", f.InputPath(), loc.GetSpan()[0]+1, loc.GetSpan()[1]+1)
+			}
+		}
+	}
+	return f.InputPath().String()
+}
+
+func equalPaths(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
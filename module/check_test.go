@@ -0,0 +1,214 @@
+package module
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/common/types"
+	pgs "github.com/lyft/protoc-gen-star"
+	pgsgo "github.com/lyft/protoc-gen-star/lang/go"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+// checkMethod returns the Check method and the packages of check.proto
+func checkMethod(t *testing.T) (pgs.Method, map[string]pgs.Package) {
+	t.Helper()
+	ast := pgs.ProcessCodeGeneratorRequest(pgs.InitMockDebugger(), load(t, "", "check.proto"))
+	entity, ok := ast.Lookup(".check.CheckService.Check")
+	if !ok {
+		t.Fatal("method check.CheckService.Check not found")
+	}
+	return entity.(pgs.Method), ast.Packages()
+}
+
+func TestModule_CheckErrors(t *testing.T) {
+	type test struct {
+		name   string
+		params string
+		expect []string
+	}
+	tests := []test{
+		{
+			name:   "cel",
+			params: "authorizer=cel",
+			expect: []string{
+				`check.proto:11:5: check.CheckService.Check rule 1: invalid expression "request.AcountId == 'x'": ERROR: <input>:1:8: undefined field 'AcountId'`,
+			},
+		},
+		{
+			name:   "cel proto messages",
+			params: "authorizer=cel,proto_messages=true",
+			expect: []string{
+				`check.proto:11:5: check.CheckService.Check rule 1: invalid expression "request.AcountId == 'x'": ERROR: <input>:1:8: undefined field 'AcountId'`,
+			},
+		},
+		{
+			name:   "type checking disabled",
+			params: "authorizer=cel,type_check=false",
+		},
+		{
+			name:   "javascript",
+			params: "authorizer=javascript",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := run(t, tt.params, "check.proto")
+			for i, want := range tt.expect {
+				if i >= len(res.errors) || !strings.HasPrefix(res.errors[i], want) {
+					t.Fatalf("expected errors %q, got %q", tt.expect, res.errors)
+				}
+			}
+			if len(res.errors) != len(tt.expect) {
+				t.Fatalf("expected errors %q, got %q", tt.expect, res.errors)
+			}
+			if _, ok := res.files["check.pb.authorizer.go"]; ok == (len(tt.expect) > 0) {
+				t.Fatalf("expected check.pb.authorizer.go to be generated only without errors: %v", res.files)
+			}
+		})
+	}
+}
+
+func TestCelChecker(t *testing.T) {
+	method, packages := checkMethod(t)
+	files, err := protoFiles(packages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := findMessage(packages, "check.User")
+	type test struct {
+		name       string
+		user       pgs.Message
+		files      *protoregistry.Files
+		expression string
+		expectErr  string
+	}
+	tests := []test{
+		{
+			name:       "go field name",
+			expression: "request.AccountId == 'x'",
+		},
+		{
+			name:       "unknown field",
+			expression: "request.AcountId == 'x'",
+			expectErr:  "undefined field 'AcountId'",
+		},
+		{
+			name:       "proto field name",
+			expression: "request.account_id == 'x'",
+			expectErr:  "undefined field 'account_id'",
+		},
+		{
+			name:       "mismatched type",
+			expression: "request.AccountId == 1",
+			expectErr:  "found no matching overload for '_==_'",
+		},
+		{
+			name:       "optional field is dyn",
+			expression: "request.Nickname == 'x' || request.Nickname == 1",
+		},
+		{
+			name:       "oneof is dyn",
+			expression: "request.Target == 'x' || request.Target == 1",
+		},
+		{
+			name:       "oneof fields are not fields of the go struct",
+			expression: "request.Name == 'x'",
+			expectErr:  "undefined field 'Name'",
+		},
+		{
+			name:       "map and repeated fields",
+			expression: "request.Labels['team'] == 'x' && 1 in request.Ids",
+		},
+		{
+			name:       "nested message is dyn",
+			expression: "request.Owner.account_id == 'x'",
+		},
+		{
+			name:       "user is a map by default",
+			expression: "user.anything == 'x'",
+		},
+		{
+			name:       "user message",
+			user:       user,
+			expression: "user.AccountId == request.AccountId && 'admin' in user.Roles",
+		},
+		{
+			name:       "unknown user field",
+			user:       user,
+			expression: "user.Admin",
+			expectErr:  "undefined field 'Admin'",
+		},
+		{
+			name:       "proto messages",
+			files:      files,
+			expression: "request.account_id == 'x' && has(request.nickname) && request.owner.account_id == 'x'",
+		},
+		{
+			name:       "proto messages unknown field",
+			files:      files,
+			expression: "request.AccountId == 'x'",
+			expectErr:  "undefined field 'AccountId'",
+		},
+		{
+			name:       "proto messages user message",
+			user:       user,
+			files:      files,
+			expression: "user.account_id == request.account_id && hasRole(user, 'admin')",
+		},
+		{
+			name:       "proto messages unknown user field",
+			user:       user,
+			files:      files,
+			expression: "user.admin",
+			expectErr:  "undefined field 'admin'",
+		},
+		{
+			name:       "library",
+			expression: "hasRole(user, 'admin') && glob(method, '/check.*')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := newCelChecker(pgsgo.InitContext(pgs.Parameters{}), tt.user, tt.files)
+			err := checker.check(method, &authorize.Rule{Expression: tt.expression})
+			switch {
+			case tt.expectErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.expectErr != "" && err == nil:
+				t.Fatalf("expected error %q", tt.expectErr)
+			case tt.expectErr != "" && !strings.Contains(err.Error(), tt.expectErr):
+				t.Fatalf("expected error %q, got %q", tt.expectErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestGoFieldProvider_Fields(t *testing.T) {
+	method, _ := checkMethod(t)
+	provider := &goFieldProvider{ctx: pgsgo.InitContext(pgs.Parameters{})}
+	fields := provider.fields(method.Input())
+	expect := map[string]*types.Type{
+		"AccountId": types.StringType,
+		// proto3 optional fields are pointers and oneofs are interfaces in the go struct
+		"Nickname": types.DynType,
+		"Target":   types.DynType,
+		"Labels":   types.NewMapType(types.StringType, types.StringType),
+		"Ids":      types.NewListType(types.IntType),
+		"Owner":    types.DynType,
+	}
+	if len(fields) != len(expect) {
+		t.Fatalf("expected fields %v, got %v", expect, fields)
+	}
+	for name, want := range expect {
+		got, ok := fields[name]
+		if !ok {
+			t.Fatalf("missing field %s: %v", name, fields)
+		}
+		if !got.IsExactType(want) {
+			t.Fatalf("expected field %s to be %s, got %s", name, want, got)
+		}
+	}
+}
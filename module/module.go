@@ -8,8 +8,10 @@ import (
 
 	pgs "github.com/lyft/protoc-gen-star"
 	pgsgo "github.com/lyft/protoc-gen-star/lang/go"
+	"google.golang.org/protobuf/reflect/protoregistry"
 
-	"github.com/autom8ter/proto/gen/authorize"
+	"github.com/autom8ter/protoc-gen-authorize/authorizer"
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 )
 
 // Module is the protoc-gen-authorizer module
@@ -17,7 +19,13 @@ import (
 type module struct {
 	*pgs.ModuleBase
 	pgsgo.Context
-	authorizer string
+	authorizer    string
+	userMessage   string
+	protoMessages bool
+	typeCheck     bool
+	missingRules  authorizer.MissingRulesPolicy
+	user          pgs.Message
+	checker       ruleChecker
 }
 
 func New() pgs.Module {
@@ -37,9 +45,57 @@ func (m *module) InitContext(c pgs.BuildContext) {
 		m.authorizer = "cel"
 	}
 	m.authorizer = strings.ToLower(m.authorizer)
+	switch m.authorizer {
+	case "cel", "javascript", "expr":
+	default:
+		m.AddError(fmt.Sprintf("authorize: unsupported authorizer %q (expected cel, javascript or expr)", m.authorizer))
+	}
+	// user_message is the fully qualified name of the proto message used as the user variable
+	// when type checking CEL expressions (ex: myapp.User)
+	m.userMessage = strings.TrimPrefix(params.Str("user_message"), ".")
+	// proto_messages enables protobuf-native evaluation of CEL expressions (see cel.WithProtoMessages)
+	protoMessages, err := params.Bool("proto_messages")
+	if err != nil {
+		m.AddError(err.Error())
+	}
+	m.protoMessages = protoMessages
+	// type_check=false disables type checking of CEL and expr expressions, for expressions that use functions registered at runtime
+	typeCheck, err := params.BoolDefault("type_check", true)
+	if err != nil {
+		m.AddError(err.Error())
+	}
+	m.typeCheck = typeCheck
+	// missing_rules is the policy for methods without rules (see authorizer.MissingRulesPolicy)
+	missingRules, err := authorizer.ParseMissingRulesPolicy(params.Str("missing_rules"))
+	if err != nil {
+		m.AddError(err.Error())
+	}
+	m.missingRules = missingRules
 }
 
 func (m *module) Execute(targets map[string]pgs.File, packages map[string]pgs.Package) []pgs.Artifact {
+	if m.userMessage != "" {
+		m.user = findMessage(packages, m.userMessage)
+		if m.user == nil {
+			m.AddError(fmt.Sprintf("authorize: user_message %s not found", m.userMessage))
+			return m.Artifacts()
+		}
+	}
+	if m.authorizer == "cel" && m.typeCheck {
+		var files *protoregistry.Files
+		if m.protoMessages {
+			var err error
+			files, err = protoFiles(packages)
+			if err != nil {
+				m.AddError(err.Error())
+				return m.Artifacts()
+			}
+		}
+		m.checker = newCelChecker(m.Context, m.user, files)
+	}
+	if m.authorizer == "expr" && m.typeCheck {
+		m.checker = exprChecker{}
+	}
 	for _, f := range targets {
 		if f.BuildTarget() {
 			m.generate(f)
@@ -49,84 +105,254 @@ func (m *module) Execute(targets map[string]pgs.File, packages map[string]pgs.Pa
 }
 
 func (m *module) generate(f pgs.File) {
-	var rules = map[string]*authorize.RuleSet{}
-	for _, s := range f.Services() {
-		for _, method := range s.Methods() {
+	var (
+		rules     = map[string]*authorize.RuleSet{}
+		fileRules authorize.RuleSet
+		// locations maps each rule to the source location of the option that declared it
+		locations = map[*authorize.Rule]string{}
+		invalid   bool
+	)
+	fileOk, err := f.Extension(authorize.E_FileRules, &fileRules)
+	if err != nil {
+		m.AddError(err.Error())
+		return
+	}
+	setLocations(locations, &fileRules, sourceLocation(f, fileOptionsPath, extensionNumber))
+	for i, s := range f.Services() {
+		var svcRules authorize.RuleSet
+		svcOk, err := s.Extension(authorize.E_ServiceRules, &svcRules)
+		if err != nil {
+			m.AddError(err.Error())
+			continue
+		}
+		setLocations(locations, &svcRules, sourceLocation(f, fileServicePath, int32(i), serviceOptionsPath, extensionNumber))
+		var inherited *authorize.RuleSet
+		switch {
+		case fileOk && svcOk:
+			inherited = mergeRuleSets(&fileRules, &svcRules)
+		case fileOk:
+			inherited = &fileRules
+		case svcOk:
+			inherited = &svcRules
+		}
+		var unannotated []string
+		for j, method := range s.Methods() {
 			var ruleSet authorize.RuleSet
 			ok, err := method.Extension(authorize.E_Rules, &ruleSet)
 			if err != nil {
 				m.AddError(err.Error())
 				continue
 			}
-			if !ok {
-				continue
-			}
+			setLocations(locations, &ruleSet, sourceLocation(f, fileServicePath, int32(i), serviceMethodPath, int32(j), methodOptionsPath, extensionNumber))
 			// EchoService_Echo_FullMethodName
 			name := fmt.Sprintf("%s_%s_FullMethodName", s.Name().UpperCamelCase(), method.Name().UpperCamelCase())
-			rules[name] = &ruleSet
+			switch {
+			case ok && inherited != nil:
+				rules[name] = mergeRuleSets(inherited, &ruleSet)
+			case ok:
+				rules[name] = &ruleSet
+			case inherited != nil:
+				rules[name] = inherited
+			default:
+				unannotated = append(unannotated, fmt.Sprintf("%s: method %s has no authorize rules",
+					sourceLocation(f, fileServicePath, int32(i), serviceMethodPath, int32(j)), strings.TrimPrefix(method.FullyQualifiedName(), ".")))
+				continue
+			}
+			for _, path := range rules[name].GetCache().GetRequestFields() {
+				if err := checkCacheField(method.Input(), path); err != nil {
+					invalid = true
+					m.AddError(fmt.Sprintf("%s: %s: invalid cache request field %q: %v", sourceLocation(f, fileServicePath, int32(i), serviceMethodPath, int32(j)),
+						strings.TrimPrefix(method.FullyQualifiedName(), "."), path, err))
+				}
+			}
+			if m.checker != nil {
+				for k, rule := range rules[name].GetRules() {
+					if err := m.checker.check(method, rule); err != nil {
+						invalid = true
+						m.AddError(fmt.Sprintf("%s: %s rule %d: invalid expression %q: %v", locations[rule], strings.TrimPrefix(method.FullyQualifiedName(), "."), k, rule.GetExpression(), err))
+					}
+				}
+			}
+		}
+		// methods without rules in a service with rules are easy to miss - they are authorized by the missing_rules policy
+		if len(unannotated) > 0 && len(unannotated) < len(s.Methods()) {
+			for _, warning := range unannotated {
+				m.Logf("warning: %s and is authorized by the %s missing_rules policy", warning, m.missingRules)
+			}
 		}
 	}
-	if len(rules) == 0 {
+	if len(rules) == 0 || invalid {
 		return
 	}
 	name := f.InputPath().SetExt(".pb.authorizer.go").String()
-	var (
-		t   *template.Template
-		err error
-	)
+	var t *template.Template
 	switch m.authorizer {
 	case "javascript":
-		t, err = template.New("authorizer").Parse(javascriptTmpl)
-		if err != nil {
-			m.AddError(err.Error())
-			return
-		}
+		t, err = template.New("authorizer").Parse(javascriptTmpl + rulesTmpl)
 	case "cel":
-		t, err = template.New("authorizer").Parse(celTmpl)
+		t, err = template.New("authorizer").Parse(celTmpl + rulesTmpl)
+	case "expr":
+		t, err = template.New("authorizer").Parse(exprTmpl + rulesTmpl)
+	default:
+		// unsupported authorizers are reported by InitContext
+		return
+	}
+	if err != nil {
+		m.AddError(err.Error())
+		return
 	}
 
+	data := templateData{
+		Package:       m.Context.PackageName(f).String(),
+		Rules:         rules,
+		ProtoMessages: m.protoMessages,
+		MissingRules:  missingRulesConstants[m.missingRules],
+	}
+	if m.protoMessages && m.user != nil {
+		data.UserMessage = m.Context.Name(m.user).String()
+		if path := m.Context.ImportPath(m.user); path != m.Context.ImportPath(f) {
+			data.UserImport = fmt.Sprintf("userpb %q", path)
+			data.UserMessage = "userpb." + data.UserMessage
+		}
+	}
 	buffer := &bytes.Buffer{}
-	if err := t.Execute(buffer, templateData{
-		Package: m.Context.PackageName(f).String(),
-		Rules:   rules,
-	}); err != nil {
+	if err := t.Execute(buffer, data); err != nil {
 		m.AddError(err.Error())
 		return
 	}
 	m.AddGeneratorFile(name, buffer.String())
 }
 
+// field numbers used to locate authorize options in a file's source code info
+const (
+	fileServicePath    = 6
+	fileOptionsPath    = 8
+	serviceMethodPath  = 2
+	serviceOptionsPath = 3
+	methodOptionsPath  = 4
+	extensionNumber    = 73902
+)
+
+func setLocations(locations map[*authorize.Rule]string, rules *authorize.RuleSet, location string) {
+	for _, rule := range rules.GetRules() {
+		locations[rule] = location
+	}
+}
+
+// checkCacheField returns an error if the path of proto field names isn't a field of the message.
+// Every field of the path but the last must be a singular message field
+func checkCacheField(msg pgs.Message, path string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		var field pgs.Field
+		for _, f := range msg.Fields() {
+			if f.Name().String() == name {
+				field = f
+				break
+			}
+		}
+		if field == nil {
+			return fmt.Errorf("%s has no field %q", strings.TrimPrefix(msg.FullyQualifiedName(), "."), name)
+		}
+		if i < len(names)-1 {
+			if !field.Type().IsEmbed() {
+				return fmt.Errorf("%q is not a message field", name)
+			}
+			msg = field.Type().Embed()
+		}
+	}
+	return nil
+}
+
+// findMessage returns the message with the given fully qualified name (without the leading dot)
+func findMessage(packages map[string]pgs.Package, name string) pgs.Message {
+	for _, pkg := range packages {
+		for _, f := range pkg.Files() {
+			for _, msg := range f.AllMessages() {
+				if typeName(msg) == name {
+					return msg
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// mergeRuleSets merges a RuleSet with the rules it inherits from its service or file, unless the child replaces them.
+// The inherited deny rules are evaluated first so that they can't be overridden by a child allow rule, then the
+// child's rules, then the inherited allow rules.
+func mergeRuleSets(inherited *authorize.RuleSet, child *authorize.RuleSet) *authorize.RuleSet {
+	if child.GetInheritance() == authorize.Inheritance_INHERITANCE_REPLACE {
+		return child
+	}
+	var denies, allows []*authorize.Rule
+	for _, rule := range inherited.GetRules() {
+		if rule.GetEffect() == authorize.Effect_EFFECT_DENY {
+			denies = append(denies, rule)
+		} else {
+			allows = append(allows, rule)
+		}
+	}
+	rules := append(append(denies, child.GetRules()...), allows...)
+	merged := &authorize.RuleSet{
+		Rules:                   rules,
+		Algorithm:               child.GetAlgorithm(),
+		AuthorizeStreamMessages: child.GetAuthorizeStreamMessages() || inherited.GetAuthorizeStreamMessages(),
+		DryRun:                  child.GetDryRun() || inherited.GetDryRun(),
+		Denial:                  child.GetDenial(),
+		Cache:                   child.GetCache(),
+	}
+	if merged.Denial == nil {
+		merged.Denial = inherited.GetDenial()
+	}
+	if merged.Cache == nil {
+		merged.Cache = inherited.GetCache()
+	}
+	if merged.Algorithm == authorize.CombiningAlgorithm_COMBINING_ALGORITHM_UNSPECIFIED {
+		merged.Algorithm = inherited.GetAlgorithm()
+	}
+	return merged
+}
+
 type templateData struct {
-	Package string
-	Rules   map[string]*authorize.RuleSet
+	Package       string
+	Rules         map[string]*authorize.RuleSet
+	ProtoMessages bool
+	// UserMessage is the go type of the user message (ex: userpb.User)
+	UserMessage string
+	// UserImport is the import of the user message's go package if it differs from the generated file's package
+	UserImport string
+	// MissingRules is the authorizer constant of the missing_rules policy if it isn't the default
+	MissingRules string
+}
+
+var missingRulesConstants = map[authorizer.MissingRulesPolicy]string{
+	authorizer.MissingRulesDeny:  "MissingRulesDeny",
+	authorizer.MissingRulesAllow: "MissingRulesAllow",
+	authorizer.MissingRulesError: "MissingRulesError",
 }
 
 var javascriptTmpl = `
 package {{ .Package }}
 
 import (
-	"github.com/autom8ter/proto/gen/authorize"
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 
-	"github.com/autom8ter/protoc-gen-authorize/authorizer/javascript"
+	{{ if .MissingRules }}"github.com/autom8ter/protoc-gen-authorize/authorizer"
+	{{ end }}"github.com/autom8ter/protoc-gen-authorize/authorizer/javascript"
 )
 
 // NewAuthorizer returns a new javascript authorizer. The rules map is a map of method names to RuleSets. The RuleSets are used to
-// authorize the method. The RuleSets are evaluated in order and the first rule that evaluates to true will authorize
-// the request. The mapping can be generated with the protoc-gen-authorize plugin.
+// authorize the method. The rules of a RuleSet are combined using the RuleSet's combining algorithm - by default,
+// the effect of the first rule that evaluates to true is applied to the request.
+// The mapping can be generated with the protoc-gen-authorize plugin.
 func NewAuthorizer(opts ...javascript.Opt) (*javascript.JavascriptAuthorizer, error) {
-	return javascript.NewJavascriptAuthorizer(map[string]*authorize.RuleSet{
-	{{- range $key, $value := .Rules }}
-	{{$key}}: {
-		Rules: []*authorize.Rule{
-		{{- range $value.Rules }}
-			{
-				Expression: "{{ .Expression }}",
-			},
-		{{- end }}
-		},
-	},
+	{{- if .MissingRules }}
+	opts = append([]javascript.Opt{
+		javascript.WithMissingRulesPolicy(authorizer.{{ .MissingRules }}),
+	}, opts...)
 	{{- end }}
-}, opts...)
+	return javascript.NewJavascriptAuthorizer({{ template "rules" .Rules }}, opts...)
 }
 `
 
@@ -134,27 +360,133 @@ var celTmpl = `
 package {{ .Package }}
 
 import (
-	"github.com/autom8ter/proto/gen/authorize"
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
 
-	"github.com/autom8ter/protoc-gen-authorize/authorizer/cel"
+	{{ if .MissingRules }}"github.com/autom8ter/protoc-gen-authorize/authorizer"
+	{{ end }}"github.com/autom8ter/protoc-gen-authorize/authorizer/cel"
+	{{- if .UserImport }}
+	{{ .UserImport }}
+	{{- end }}
 )
 
-// NewAuthorizer returns a new javascript authorizer. The rules map is a map of method names to RuleSets. The RuleSets are used to
-// authorize the method. The RuleSets are evaluated in order and the first rule that evaluates to true will authorize
-// the request. The mapping can be generated with the protoc-gen-authorize plugin.
+// NewAuthorizer returns a new CEL authorizer. The rules map is a map of method names to RuleSets. The RuleSets are used to
+// authorize the method. The rules of a RuleSet are combined using the RuleSet's combining algorithm - by default,
+// the effect of the first rule that evaluates to true is applied to the request.
+// The mapping can be generated with the protoc-gen-authorize plugin.
 func NewAuthorizer(opts ...cel.Opt) (*cel.CelAuthorizer, error) {
-	return cel.NewCelAuthorizer(map[string]*authorize.RuleSet{
-	{{- range $key, $value := .Rules }}
+	{{- if or .ProtoMessages .MissingRules }}
+	opts = append([]cel.Opt{
+		{{- if .ProtoMessages }}
+		cel.WithProtoMessages(),
+		{{- end }}
+		{{- if .UserMessage }}
+		cel.WithUserMessage(&{{ .UserMessage }}{}),
+		{{- end }}
+		{{- if .MissingRules }}
+		cel.WithMissingRulesPolicy(authorizer.{{ .MissingRules }}),
+		{{- end }}
+	}, opts...)
+	{{- end }}
+	return cel.NewCelAuthorizer({{ template "rules" .Rules }}, opts...)
+}
+`
+
+var exprTmpl = `
+package {{ .Package }}
+
+import (
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+
+	{{ if .MissingRules }}"github.com/autom8ter/protoc-gen-authorize/authorizer"
+	{{ end }}"github.com/autom8ter/protoc-gen-authorize/authorizer/expr"
+)
+
+// NewAuthorizer returns a new expr authorizer. The rules map is a map of method names to RuleSets. The RuleSets are used to
+// authorize the method. The rules of a RuleSet are combined using the RuleSet's combining algorithm - by default,
+// the effect of the first rule that evaluates to true is applied to the request.
+// The mapping can be generated with the protoc-gen-authorize plugin. Expressions that use the variables or functions
+// of expr.WithVariables or expr.WithOptions must be generated with the type_check=false plugin option.
+func NewAuthorizer(opts ...expr.Opt) (*expr.ExprAuthorizer, error) {
+	{{- if .MissingRules }}
+	opts = append([]expr.Opt{
+		expr.WithMissingRulesPolicy(authorizer.{{ .MissingRules }}),
+	}, opts...)
+	{{- end }}
+	return expr.NewExprAuthorizer({{ template "rules" .Rules }}, opts...)
+}
+`
+
+var rulesTmpl = `
+{{- define "rules" -}}
+map[string]*authorize.RuleSet{
+	{{- range $key, $value := . }}
 	{{$key}}: {
 		Rules: []*authorize.Rule{
 		{{- range $value.Rules }}
 			{
-				Expression: "{{ .Expression }}",
+				Expression: {{ printf "%q" .Expression }},
+				{{- if .Name }}
+				Name: {{ printf "%q" .Name }},
+				{{- end }}
+				{{- if .Effect }}
+				Effect: authorize.Effect_{{ .Effect }},
+				{{- end }}
+				{{- if .Denial }}
+				Denial: {{ template "denial" .Denial }},
+				{{- end }}
+				{{- if .CostLimit }}
+				CostLimit: {{ .CostLimit }},
+				{{- end }}
 			},
 		{{- end }}
 		},
+		{{- if $value.Algorithm }}
+		Algorithm: authorize.CombiningAlgorithm_{{ $value.Algorithm }},
+		{{- end }}
+		{{- if $value.AuthorizeStreamMessages }}
+		AuthorizeStreamMessages: true,
+		{{- end }}
+		{{- if $value.DryRun }}
+		DryRun: true,
+		{{- end }}
+		{{- if $value.Denial }}
+		Denial: {{ template "denial" $value.Denial }},
+		{{- end }}
+		{{- if $value.Cache }}
+		Cache: &authorize.DecisionCache{
+			{{- if $value.Cache.RequestFields }}
+			RequestFields: []string{ {{- range $i, $field := $value.Cache.RequestFields }}{{ if $i }}, {{ end }}{{ printf "%q" $field }}{{ end -}} },
+			{{- end }}
+			{{- if $value.Cache.MetadataKeys }}
+			MetadataKeys: []string{ {{- range $i, $key := $value.Cache.MetadataKeys }}{{ if $i }}, {{ end }}{{ printf "%q" $key }}{{ end -}} },
+			{{- end }}
+		},
+		{{- end }}
+	},
+	{{- end }}
+}
+{{- end -}}
+{{- define "denial" -}}
+&authorize.Denial{
+	{{- if .Message }}
+	Message: {{ printf "%q" .Message }},
+	{{- end }}
+	{{- if .Code }}
+	Code: {{ .Code }},
+	{{- end }}
+	{{- if .Reason }}
+	Reason: {{ printf "%q" .Reason }},
+	{{- end }}
+	{{- if .Domain }}
+	Domain: {{ printf "%q" .Domain }},
+	{{- end }}
+	{{- if .Metadata }}
+	Metadata: map[string]string{
+		{{- range $key, $value := .Metadata }}
+		{{ printf "%q" $key }}: {{ printf "%q" $value }},
+		{{- end }}
 	},
 	{{- end }}
-}, opts...)
 }
+{{- end -}}
 `
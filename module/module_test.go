@@ -0,0 +1,373 @@
+package module
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	pgs "github.com/lyft/protoc-gen-star"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/autom8ter/protoc-gen-authorize/gen/authorize"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+// load compiles the proto files of testdata into a code generation request, as protoc would
+func load(t *testing.T, params string, files ...string) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{"testdata", "../proto"},
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	compiled, err := compiler.Compile(context.Background(), files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: files,
+		Parameter:      proto.String(params),
+	}
+	// dependencies must precede the files that import them
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := 0; i < fd.Imports().Len(); i++ {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, f := range compiled {
+		add(f)
+	}
+	// round trip the request so that the authorize options are decoded with the registered extensions
+	bits, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(bits, req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+type result struct {
+	// files are the contents of the generated files by name
+	files map[string]string
+	// errors are the generator errors
+	errors []string
+	// logs is the logged output of the module
+	logs string
+}
+
+// run executes the module against the proto files of testdata with the given plugin parameters
+func run(t *testing.T, params string, files ...string) result {
+	t.Helper()
+	req := load(t, params, files...)
+	debugger := pgs.InitMockDebugger()
+	ast := pgs.ProcessCodeGeneratorRequest(debugger, req)
+	m := New()
+	m.InitContext(pgs.Context(debugger, pgs.ParseParameters(params), "."))
+	res := result{files: map[string]string{}}
+	for _, artifact := range m.Execute(ast.Targets(), ast.Packages()) {
+		switch a := artifact.(type) {
+		case pgs.GeneratorFile:
+			res.files[a.Name] = a.Contents
+		case pgs.GeneratorError:
+			res.errors = append(res.errors, a.Message)
+		}
+	}
+	logs, err := io.ReadAll(debugger.Output())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.logs = string(logs)
+	return res
+}
+
+// message returns the message of the proto files of testdata with the given fully qualified name
+func message(t *testing.T, name string, files ...string) pgs.Message {
+	t.Helper()
+	ast := pgs.ProcessCodeGeneratorRequest(pgs.InitMockDebugger(), load(t, "", files...))
+	msg := findMessage(ast.Packages(), name)
+	if msg == nil {
+		t.Fatalf("message %s not found", name)
+	}
+	return msg
+}
+
+func TestModule_Inheritance(t *testing.T) {
+	type test struct {
+		name   string
+		params string
+		golden string
+	}
+	tests := []test{
+		{
+			name:   "cel",
+			params: "authorizer=cel",
+			golden: "inheritance.cel.golden",
+		},
+		{
+			name:   "javascript",
+			params: "authorizer=javascript,missing_rules=deny",
+			golden: "inheritance.javascript.golden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := run(t, tt.params, "inheritance.proto")
+			if len(res.errors) > 0 {
+				t.Fatalf("unexpected errors: %v", res.errors)
+			}
+			got, ok := res.files["inheritance.pb.authorizer.go"]
+			if !ok {
+				t.Fatalf("inheritance.pb.authorizer.go was not generated: %v", res.files)
+			}
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Fatalf("generated code does not match %s (run go test -update to update it):\n%s", path, got)
+			}
+		})
+	}
+}
+
+func TestModule_Warnings(t *testing.T) {
+	res := run(t, "authorizer=cel", "warnings.proto")
+	if len(res.errors) > 0 {
+		t.Fatalf("unexpected errors: %v", res.errors)
+	}
+	const want = "warning: warnings.proto:15:3: method warnings.PartialService.Unannotated has no authorize rules and is authorized by the allow_if_service_annotated missing_rules policy"
+	if !strings.Contains(res.logs, want) {
+		t.Fatalf("expected warning %q, got: %s", want, res.logs)
+	}
+	// methods of services without any rules are not reported
+	if strings.Contains(res.logs, "UnannotatedService") {
+		t.Fatalf("unexpected warning for UnannotatedService: %s", res.logs)
+	}
+	if _, ok := res.files["warnings.pb.authorizer.go"]; !ok {
+		t.Fatalf("warnings.pb.authorizer.go was not generated: %v", res.files)
+	}
+}
+
+func TestModule_CacheFields(t *testing.T) {
+	res := run(t, "authorizer=cel", "cache.proto")
+	want := []string{
+		`cache.proto:16:3: cache.CacheService.Invalid: invalid cache request field "account.missing": cache.Account has no field "missing"`,
+	}
+	if strings.Join(res.errors, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected errors %q, got %q", want, res.errors)
+	}
+	// files with invalid rules are not generated
+	if len(res.files) > 0 {
+		t.Fatalf("unexpected generated files: %v", res.files)
+	}
+}
+
+func TestCheckCacheField(t *testing.T) {
+	request := message(t, "cache.Request", "cache.proto")
+	type test struct {
+		name      string
+		path      string
+		expectErr string
+	}
+	tests := []test{
+		{
+			name: "field",
+			path: "account_id",
+		},
+		{
+			name: "nested field",
+			path: "account.id",
+		},
+		{
+			name:      "unknown field",
+			path:      "missing",
+			expectErr: `cache.Request has no field "missing"`,
+		},
+		{
+			name:      "unknown nested field",
+			path:      "account.missing",
+			expectErr: `cache.Account has no field "missing"`,
+		},
+		{
+			name:      "scalar parent",
+			path:      "account_id.id",
+			expectErr: `"account_id" is not a message field`,
+		},
+		{
+			name:      "repeated parent",
+			path:      "accounts.id",
+			expectErr: `"accounts" is not a message field`,
+		},
+		{
+			name:      "go field name",
+			path:      "AccountId",
+			expectErr: `cache.Request has no field "AccountId"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCacheField(request, tt.path)
+			switch {
+			case tt.expectErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.expectErr != "" && err == nil:
+				t.Fatalf("expected error %q", tt.expectErr)
+			case tt.expectErr != "" && err.Error() != tt.expectErr:
+				t.Fatalf("expected error %q, got %q", tt.expectErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestMergeRuleSets(t *testing.T) {
+	rule := func(name string) *authorize.Rule {
+		return &authorize.Rule{Name: name, Expression: "true"}
+	}
+	names := func(rules *authorize.RuleSet) []string {
+		var names []string
+		for _, r := range rules.GetRules() {
+			names = append(names, r.GetName())
+		}
+		return names
+	}
+	type test struct {
+		name      string
+		inherited *authorize.RuleSet
+		child     *authorize.RuleSet
+		expect    *authorize.RuleSet
+	}
+	tests := []test{
+		{
+			name: "child rules are evaluated first",
+			inherited: &authorize.RuleSet{
+				Rules:     []*authorize.Rule{rule("service")},
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+				Denial:    &authorize.Denial{Message: "service"},
+				Cache:     &authorize.DecisionCache{MetadataKeys: []string{"x-tenant"}},
+			},
+			child: &authorize.RuleSet{
+				Rules:  []*authorize.Rule{rule("method")},
+				DryRun: true,
+			},
+			expect: &authorize.RuleSet{
+				Rules:     []*authorize.Rule{rule("method"), rule("service")},
+				Algorithm: authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+				DryRun:    true,
+				Denial:    &authorize.Denial{Message: "service"},
+				Cache:     &authorize.DecisionCache{MetadataKeys: []string{"x-tenant"}},
+			},
+		},
+		{
+			name: "child settings override inherited settings",
+			inherited: &authorize.RuleSet{
+				Rules:                   []*authorize.Rule{rule("service")},
+				Algorithm:               authorize.CombiningAlgorithm_COMBINING_ALGORITHM_DENY_OVERRIDES,
+				AuthorizeStreamMessages: true,
+				Denial:                  &authorize.Denial{Message: "service"},
+				Cache:                   &authorize.DecisionCache{MetadataKeys: []string{"x-tenant"}},
+			},
+			child: &authorize.RuleSet{
+				Rules:       []*authorize.Rule{rule("method")},
+				Algorithm:   authorize.CombiningAlgorithm_COMBINING_ALGORITHM_PERMIT_OVERRIDES,
+				Inheritance: authorize.Inheritance_INHERITANCE_EXTEND,
+				Denial:      &authorize.Denial{Message: "method"},
+				Cache:       &authorize.DecisionCache{RequestFields: []string{"account_id"}},
+			},
+			expect: &authorize.RuleSet{
+				Rules:                   []*authorize.Rule{rule("method"), rule("service")},
+				Algorithm:               authorize.CombiningAlgorithm_COMBINING_ALGORITHM_PERMIT_OVERRIDES,
+				AuthorizeStreamMessages: true,
+				Denial:                  &authorize.Denial{Message: "method"},
+				Cache:                   &authorize.DecisionCache{RequestFields: []string{"account_id"}},
+			},
+		},
+		{
+			name: "inherited deny rules are evaluated first",
+			inherited: &authorize.RuleSet{
+				Rules: []*authorize.Rule{
+					rule("service-allow"),
+					{Name: "service-deny", Expression: "true", Effect: authorize.Effect_EFFECT_DENY},
+				},
+			},
+			child: &authorize.RuleSet{
+				Rules: []*authorize.Rule{rule("method")},
+			},
+			expect: &authorize.RuleSet{
+				Rules: []*authorize.Rule{
+					{Name: "service-deny", Expression: "true", Effect: authorize.Effect_EFFECT_DENY},
+					rule("method"),
+					rule("service-allow"),
+				},
+			},
+		},
+		{
+			name: "replace",
+			inherited: &authorize.RuleSet{
+				Rules:  []*authorize.Rule{rule("service")},
+				DryRun: true,
+			},
+			child: &authorize.RuleSet{
+				Rules:       []*authorize.Rule{rule("method")},
+				Inheritance: authorize.Inheritance_INHERITANCE_REPLACE,
+			},
+			expect: &authorize.RuleSet{
+				Rules:       []*authorize.Rule{rule("method")},
+				Inheritance: authorize.Inheritance_INHERITANCE_REPLACE,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inherited := proto.Clone(tt.inherited).(*authorize.RuleSet)
+			merged := mergeRuleSets(tt.inherited, tt.child)
+			if !proto.Equal(merged, tt.expect) {
+				t.Fatalf("expected %v, got %v", tt.expect, merged)
+			}
+			if !proto.Equal(tt.inherited, inherited) {
+				t.Fatalf("inherited rule set was modified: %v", tt.inherited)
+			}
+		})
+	}
+	t.Run("shared inherited rule set", func(t *testing.T) {
+		// the inherited rule set is shared by every method of a service, so merging must not write to its rules
+		inherited := &authorize.RuleSet{Rules: make([]*authorize.Rule, 1, 4)}
+		inherited.Rules[0] = rule("service")
+		a := mergeRuleSets(inherited, &authorize.RuleSet{Rules: []*authorize.Rule{rule("a")}})
+		b := mergeRuleSets(inherited, &authorize.RuleSet{Rules: []*authorize.Rule{rule("b")}})
+		if got := strings.Join(names(a), ","); got != "a,service" {
+			t.Fatalf("expected a,service, got %s", got)
+		}
+		if got := strings.Join(names(b), ","); got != "b,service" {
+			t.Fatalf("expected b,service, got %s", got)
+		}
+		if got := strings.Join(names(inherited), ","); got != "service" {
+			t.Fatalf("expected service, got %s", got)
+		}
+	})
+}